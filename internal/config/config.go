@@ -1,6 +1,9 @@
 package config
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,9 +20,17 @@ type Config struct {
 		BindIP string `yaml:"bind_ip" env-default:"127.0.0.1"` // Есть дефолт значения
 		Port   string `yaml:"port" env-default:"8080"`         // Есть дефолт значения
 	} `yaml:"listen"`
-	Storage   StorageConfig `yaml:"storage"`
-	Clients   ClientsConfig `yaml:"clients"`
-	AppSecret string        `yaml:"app_secret" env-required:"true" env:"APP_SECRET"`
+	Storage      StorageConfig      `yaml:"storage"`
+	Clients      ClientsConfig      `yaml:"clients"`
+	Trades       TradesConfig       `yaml:"trades"`
+	Market       MarketConfig       `yaml:"market"`
+	Auctions     AuctionsConfig     `yaml:"auctions"`
+	PriceHistory PriceHistoryConfig `yaml:"price_history"`
+	Stats        StatsConfig        `yaml:"stats"`
+	Receipts     ReceiptsConfig     `yaml:"receipts"`
+	TradeRules   []TradeRuleConfig  `yaml:"trade_rules"`
+	Fraud        FraudConfig        `yaml:"fraud"`
+	AppSecret    string             `yaml:"app_secret" env-required:"true" env:"APP_SECRET"`
 }
 
 type StorageConfig struct {
@@ -41,6 +52,78 @@ type ClientsConfig struct {
 	Item Client `yaml:"item"`
 }
 
+type TradesConfig struct {
+	TTL             time.Duration `yaml:"ttl" env-default:"720h"`              // Сколько живет pending-трейд
+	ExpireInterval  time.Duration `yaml:"expire_interval" env-default:"5m"`    // Как часто запускать экспирацию
+	ExpireBatchSize int           `yaml:"expire_batch_size" env-default:"500"` // Сколько трейдов обрабатывать за раз
+}
+
+type MarketConfig struct {
+	DefaultCurrency string `yaml:"default_currency" env-default:"USD"` // Валюта лота, если не указана и нет цены предмета
+}
+
+type AuctionsConfig struct {
+	SnipingWindow    time.Duration `yaml:"sniping_window" env-default:"2m"`    // Ставка в последние минуты продлевает аукцион
+	SnipingExtension time.Duration `yaml:"sniping_extension" env-default:"2m"` // Сколько времени остается после такой ставки
+	MaxDuration      time.Duration `yaml:"max_duration" env-default:"336h"`    // Максимальная длительность аукциона
+	CloseInterval    time.Duration `yaml:"close_interval" env-default:"1m"`    // Как часто закрывать завершившиеся аукционы
+	CloseBatchSize   int           `yaml:"close_batch_size" env-default:"100"` // Сколько аукционов закрывать за раз
+	MinRarity        string        `yaml:"min_rarity" env-default:"mythical"`  // Самая частая редкость, которую можно выставить на аукцион
+}
+
+type PriceHistoryConfig struct {
+	RollupInterval time.Duration `yaml:"rollup_interval" env-default:"15m"` // Как часто пересчитывать историю цен
+	RollupLookback time.Duration `yaml:"rollup_lookback" env-default:"48h"` // За какой период пересчитывать бакеты
+}
+
+type StatsConfig struct {
+	RefreshInterval time.Duration `yaml:"refresh_interval" env-default:"10m"` // Как часто обновлять материализованные представления статистики
+}
+
+type ReceiptsConfig struct {
+	SigningKey string `yaml:"-" env:"RECEIPT_SIGNING_KEY"` // Seed ключа Ed25519 в base64 (32 байта), только из окружения; без него чеки не выпускаются
+}
+
+// Названия правил трейдов в секции trade_rules
+const (
+	TradeRuleMaxItemsPerSide   = "max_items_per_side"
+	TradeRuleNoItemOnBothSides = "no_item_on_both_sides"
+	TradeRuleMinAccountAge     = "min_account_age_for_rarity"
+	TradeRuleMaxPendingTrades  = "max_pending_trades"
+)
+
+type TradeRuleConfig struct {
+	Rule   string        `yaml:"rule"`    // max_items_per_side, no_item_on_both_sides, min_account_age_for_rarity, max_pending_trades
+	Limit  int           `yaml:"limit"`   // Лимит для max_items_per_side и max_pending_trades
+	Rarity string        `yaml:"rarity"`  // Для min_account_age_for_rarity: правило действует на редкости выше этой
+	MinAge time.Duration `yaml:"min_age"` // Для min_account_age_for_rarity: минимальный возраст аккаунта
+}
+
+type FraudConfig struct {
+	HoldScore int                `yaml:"hold_score" env-default:"50"` // С какого суммарного балла трейд задерживается до проверки модератором
+	Checks    []FraudCheckConfig `yaml:"checks"`
+}
+
+// Названия проверок в секции fraud.checks
+const (
+	FraudCheckLopsidedValue   = "lopsided_value"
+	FraudCheckNewAccountBurst = "new_account_burst"
+	FraudCheckPingPong        = "ping_pong"
+	FraudCheckSharedUserAgent = "shared_user_agent"
+)
+
+type FraudCheckConfig struct {
+	Check       string        `yaml:"check"`        // lopsided_value, new_account_burst, ping_pong, shared_user_agent
+	Weight      int           `yaml:"weight"`       // Сколько баллов добавляет сработавшая проверка
+	Limit       int           `yaml:"limit"`        // Порог для new_account_burst, ping_pong и shared_user_agent
+	MinFairness float64       `yaml:"min_fairness"` // Для lopsided_value: трейды с fairness ниже считаются перекошенными
+	MaxAge      time.Duration `yaml:"max_age"`      // Для new_account_burst: до какого возраста аккаунт считается новым
+	Window      time.Duration `yaml:"window"`       // Для new_account_burst и ping_pong: за какой период считать трейды
+}
+
+// ItemRarities — редкости предметов Dota 2 от самой частой к самой редкой, в нижнем регистре
+var ItemRarities = []string{"common", "uncommon", "rare", "mythical", "legendary", "ancient", "immortal", "arcana"}
+
 var instance *Config
 var once sync.Once
 
@@ -54,7 +137,101 @@ func GetConfig() *Config { // SINGLETON
 			logger.Info(help)
 			logger.Fatal(err)
 		}
+		if err := instance.validate(); err != nil {
+			logger.Fatal(err)
+		}
 
 	})
 	return instance
 }
+
+// validate проверяет значения, без которых не могут работать фоновые задачи,
+// правила трейдов и антифрод, чтобы ошибка в config.yaml находилась при старте
+func (c *Config) validate() error {
+	switch {
+	case c.Trades.TTL <= 0:
+		return fmt.Errorf("trades.ttl must be positive, got %s", c.Trades.TTL)
+	case c.Trades.ExpireInterval <= 0:
+		return fmt.Errorf("trades.expire_interval must be positive, got %s", c.Trades.ExpireInterval)
+	case c.Trades.ExpireBatchSize <= 0:
+		return fmt.Errorf("trades.expire_batch_size must be positive, got %d", c.Trades.ExpireBatchSize)
+	case c.Auctions.CloseInterval <= 0:
+		return fmt.Errorf("auctions.close_interval must be positive, got %s", c.Auctions.CloseInterval)
+	case c.Auctions.CloseBatchSize <= 0:
+		return fmt.Errorf("auctions.close_batch_size must be positive, got %d", c.Auctions.CloseBatchSize)
+	case c.Auctions.MaxDuration <= 0:
+		return fmt.Errorf("auctions.max_duration must be positive, got %s", c.Auctions.MaxDuration)
+	case !slices.Contains(ItemRarities, strings.ToLower(c.Auctions.MinRarity)):
+		return fmt.Errorf("auctions.min_rarity: unknown rarity %q", c.Auctions.MinRarity)
+	case c.PriceHistory.RollupInterval <= 0:
+		return fmt.Errorf("price_history.rollup_interval must be positive, got %s", c.PriceHistory.RollupInterval)
+	case c.PriceHistory.RollupLookback <= 0:
+		return fmt.Errorf("price_history.rollup_lookback must be positive, got %s", c.PriceHistory.RollupLookback)
+	case c.Stats.RefreshInterval <= 0:
+		return fmt.Errorf("stats.refresh_interval must be positive, got %s", c.Stats.RefreshInterval)
+	}
+	for i, rule := range c.TradeRules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("trade_rules[%d]: %w", i, err)
+		}
+	}
+	if c.Fraud.HoldScore <= 0 {
+		return fmt.Errorf("fraud.hold_score must be positive, got %d", c.Fraud.HoldScore)
+	}
+	for i, check := range c.Fraud.Checks {
+		if err := check.validate(); err != nil {
+			return fmt.Errorf("fraud.checks[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validate проверяет, что у правила указаны нужные ему параметры
+func (r TradeRuleConfig) validate() error {
+	switch r.Rule {
+	case TradeRuleMaxItemsPerSide, TradeRuleMaxPendingTrades:
+		if r.Limit <= 0 {
+			return fmt.Errorf("%s needs a positive limit", r.Rule)
+		}
+	case TradeRuleNoItemOnBothSides:
+	case TradeRuleMinAccountAge:
+		if !slices.Contains(ItemRarities, strings.ToLower(r.Rarity)) {
+			return fmt.Errorf("%s: unknown rarity %q", r.Rule, r.Rarity)
+		}
+		if r.MinAge <= 0 {
+			return fmt.Errorf("%s needs a positive min_age", r.Rule)
+		}
+	default:
+		return fmt.Errorf("unknown rule %q", r.Rule)
+	}
+	return nil
+}
+
+// validate проверяет вес проверки и нужные ей параметры
+func (f FraudCheckConfig) validate() error {
+	if f.Weight <= 0 {
+		return fmt.Errorf("%s needs a positive weight", f.Check)
+	}
+
+	switch f.Check {
+	case FraudCheckLopsidedValue:
+		if f.MinFairness <= 0 || f.MinFairness > 1 {
+			return fmt.Errorf("%s needs min_fairness between 0 and 1", f.Check)
+		}
+	case FraudCheckNewAccountBurst:
+		if f.MaxAge <= 0 || f.Window <= 0 || f.Limit <= 0 {
+			return fmt.Errorf("%s needs a positive max_age, window and limit", f.Check)
+		}
+	case FraudCheckPingPong:
+		if f.Window <= 0 || f.Limit <= 0 {
+			return fmt.Errorf("%s needs a positive window and limit", f.Check)
+		}
+	case FraudCheckSharedUserAgent:
+		if f.Limit <= 1 {
+			return fmt.Errorf("%s needs a limit above 1", f.Check)
+		}
+	default:
+		return fmt.Errorf("unknown check %q", f.Check)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// validConfig returns a configuration that passes validate.
+func validConfig() *Config {
+	c := &Config{}
+	c.Trades = TradesConfig{TTL: 720 * time.Hour, ExpireInterval: 5 * time.Minute, ExpireBatchSize: 500}
+	c.Auctions = AuctionsConfig{MaxDuration: 336 * time.Hour, CloseInterval: time.Minute, CloseBatchSize: 100, MinRarity: "mythical"}
+	c.PriceHistory = PriceHistoryConfig{RollupInterval: 15 * time.Minute, RollupLookback: 48 * time.Hour}
+	c.Stats.RefreshInterval = 10 * time.Minute
+	c.Fraud.HoldScore = 50
+	return c
+}
+
+func TestValidateTradeRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    TradeRuleConfig
+		wantErr bool
+	}{
+		{"items per side", TradeRuleConfig{Rule: TradeRuleMaxItemsPerSide, Limit: 20}, false},
+		{"items per side without limit", TradeRuleConfig{Rule: TradeRuleMaxItemsPerSide}, true},
+		{"no item on both sides", TradeRuleConfig{Rule: TradeRuleNoItemOnBothSides}, false},
+		{"account age", TradeRuleConfig{Rule: TradeRuleMinAccountAge, Rarity: "Mythical", MinAge: time.Hour}, false},
+		{"account age for unknown rarity", TradeRuleConfig{Rule: TradeRuleMinAccountAge, Rarity: "shiny", MinAge: time.Hour}, true},
+		{"account age without min_age", TradeRuleConfig{Rule: TradeRuleMinAccountAge, Rarity: "rare"}, true},
+		{"pending trades", TradeRuleConfig{Rule: TradeRuleMaxPendingTrades, Limit: 50}, false},
+		{"pending trades with negative limit", TradeRuleConfig{Rule: TradeRuleMaxPendingTrades, Limit: -1}, true},
+		{"unknown rule", TradeRuleConfig{Rule: "max_trades_per_day", Limit: 5}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			c.TradeRules = []TradeRuleConfig{tt.rule}
+			if err := c.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateFraudChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   FraudCheckConfig
+		wantErr bool
+	}{
+		{"lopsided value", FraudCheckConfig{Check: FraudCheckLopsidedValue, Weight: 50, MinFairness: 0.2}, false},
+		{"lopsided value above 1", FraudCheckConfig{Check: FraudCheckLopsidedValue, Weight: 50, MinFairness: 1.5}, true},
+		{"without weight", FraudCheckConfig{Check: FraudCheckLopsidedValue, MinFairness: 0.2}, true},
+		{"new account burst", FraudCheckConfig{Check: FraudCheckNewAccountBurst, Weight: 30, MaxAge: time.Hour, Window: time.Hour, Limit: 5}, false},
+		{"new account burst without window", FraudCheckConfig{Check: FraudCheckNewAccountBurst, Weight: 30, MaxAge: time.Hour, Limit: 5}, true},
+		{"ping pong", FraudCheckConfig{Check: FraudCheckPingPong, Weight: 30, Window: time.Hour, Limit: 3}, false},
+		{"ping pong without limit", FraudCheckConfig{Check: FraudCheckPingPong, Weight: 30, Window: time.Hour}, true},
+		{"shared user agent", FraudCheckConfig{Check: FraudCheckSharedUserAgent, Weight: 20, Limit: 5}, false},
+		{"shared user agent of one account", FraudCheckConfig{Check: FraudCheckSharedUserAgent, Weight: 20, Limit: 1}, true},
+		{"unknown check", FraudCheckConfig{Check: "vpn", Weight: 10}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			c.Fraud.Checks = []FraudCheckConfig{tt.check}
+			if err := c.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	c := validConfig()
+	c.Fraud.HoldScore = 0
+	if err := c.validate(); err == nil {
+		t.Error("validate() accepted a zero fraud.hold_score")
+	}
+}
+
+func TestValidateIntervals(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(c *Config)
+	}{
+		{"zero trade expiry interval", func(c *Config) { c.Trades.ExpireInterval = 0 }},
+		{"zero auction close interval", func(c *Config) { c.Auctions.CloseInterval = 0 }},
+		{"negative auction close interval", func(c *Config) { c.Auctions.CloseInterval = -time.Minute }},
+		{"zero auction close batch", func(c *Config) { c.Auctions.CloseBatchSize = 0 }},
+		{"unknown auction rarity", func(c *Config) { c.Auctions.MinRarity = "shiny" }},
+		{"zero price rollup interval", func(c *Config) { c.PriceHistory.RollupInterval = 0 }},
+		{"negative price rollup lookback", func(c *Config) { c.PriceHistory.RollupLookback = -time.Hour }},
+		{"zero stats refresh interval", func(c *Config) { c.Stats.RefreshInterval = 0 }},
+	}
+
+	if err := validConfig().validate(); err != nil {
+		t.Fatalf("valid configuration rejected: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validConfig()
+			tt.change(c)
+			if err := c.validate(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
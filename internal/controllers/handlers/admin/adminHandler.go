@@ -2,8 +2,13 @@ package handleradmin
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -170,6 +175,241 @@ func (h *AdminHandler) UpdateUserRoleByUUID(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusOK)
 }
 
+func (h *AdminHandler) AddInventoryItem(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	userID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("ошибка при парсинге UUID пользователя: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	var newItem model.InventoryItem
+	if err := json.NewDecoder(r.Body).Decode(&newItem); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(newItem); err != nil {
+		errors := err.(validator.ValidationErrors)
+		http.Error(w, fmt.Sprintf("Validation error: %s", errors), http.StatusBadRequest)
+		return
+	}
+
+	id, err := model.AddInventoryItem(userID, newItem.ItemID)
+	if err != nil {
+		h.logger.Errorf("ошибка при добавлении предмета в инвентарь: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	newItem.InventoryID = id
+	newItem.UserID = userID
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(newItem)
+}
+
+// AdjustUserWallet credits or debits a user's wallet, simulating a deposit or
+// a withdrawal.
+func (h *AdminHandler) AdjustUserWallet(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	userID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("ошибка при парсинге UUID пользователя: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var adj model.WalletAdjustment
+	if err := json.NewDecoder(r.Body).Decode(&adj); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(adj); err != nil {
+		errors := err.(validator.ValidationErrors)
+		http.Error(w, fmt.Sprintf("Validation error: %s", errors), http.StatusBadRequest)
+		return
+	}
+
+	wallet, err := model.AdjustWallet(userID, adj, actor)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidWalletAdjust):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, model.ErrInsufficientFunds):
+			http.Error(w, err.Error(), http.StatusPaymentRequired)
+		default:
+			h.logger.Errorf("ошибка при изменении баланса кошелька: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(wallet)
+}
+
+// GetTopTradedItems returns the items with the most completed trades.
+func (h *AdminHandler) GetTopTradedItems(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.writeStats(w, r, func(query model.StatsQuery) (any, error) {
+		return model.LoadTopTradedItems(query)
+	})
+}
+
+// GetTradeCounts returns the number of trades per day and status.
+func (h *AdminHandler) GetTradeCounts(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.writeStats(w, r, func(query model.StatsQuery) (any, error) {
+		return model.LoadTradeCounts(query)
+	})
+}
+
+// GetAcceptanceStats returns the average and median time to acceptance.
+func (h *AdminHandler) GetAcceptanceStats(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.writeStats(w, r, func(query model.StatsQuery) (any, error) {
+		return model.LoadAcceptanceStats(query)
+	})
+}
+
+// GetTraderStats returns the number of active traders.
+func (h *AdminHandler) GetTraderStats(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.writeStats(w, r, func(query model.StatsQuery) (any, error) {
+		return model.LoadTraderStats(query)
+	})
+}
+
+func (h *AdminHandler) writeStats(w http.ResponseWriter, r *http.Request, load func(model.StatsQuery) (any, error)) {
+	query, err := parseStatsQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := load(query)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidStatsQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Errorf("ошибка при получении статистики: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stats)
+}
+
+// parseStatsQuery reads the filters of the /api/admin/stats endpoints. from
+// and to are days in the YYYY-MM-DD format.
+func parseStatsQuery(values url.Values) (model.StatsQuery, error) {
+	var query model.StatsQuery
+
+	days := map[string]*time.Time{
+		"from": &query.From,
+		"to":   &query.To,
+	}
+	for name, dst := range days {
+		if v := values.Get(name); v != "" {
+			day, err := time.Parse(time.DateOnly, v)
+			if err != nil {
+				return model.StatsQuery{}, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*dst = day
+		}
+	}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return model.StatsQuery{}, fmt.Errorf("invalid limit: %v", err)
+		}
+		query.Limit = limit
+	}
+
+	return query, nil
+}
+
+// GetTradeReviews returns the queue of trades held for review, highest
+// score first. ?status= selects resolved reviews instead of open ones.
+func (h *AdminHandler) GetTradeReviews(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid limit: %v", err), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	reviews, err := model.LoadTradeReviews(r.URL.Query().Get("status"), limit)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidReviewQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Errorf("ошибка при получении очереди проверки трейдов: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// ApproveTradeReview releases a held trade.
+func (h *AdminHandler) ApproveTradeReview(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.resolveTradeReview(w, r, params, model.ApproveTradeReview)
+}
+
+// RejectTradeReview rejects a held trade and returns its items from escrow.
+func (h *AdminHandler) RejectTradeReview(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.resolveTradeReview(w, r, params, model.RejectTradeReview)
+}
+
+func (h *AdminHandler) resolveTradeReview(w http.ResponseWriter, r *http.Request, params httprouter.Params, resolve func(string, model.ReviewResolution, *model.Token) (*model.TradeReview, error)) {
+	actor, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var res model.ReviewResolution
+	if err := json.NewDecoder(r.Body).Decode(&res); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	review, err := resolve(params.ByName("uuid"), res, actor)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrTradeReviewNotFound):
+			http.Error(w, "Review not found", http.StatusNotFound)
+		case errors.Is(err, model.ErrTradeReviewResolved):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case errors.Is(err, model.ErrInvalidReviewQuery):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			h.logger.Errorf("ошибка при закрытии проверки трейда: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
+
 func (h *AdminHandler) DeleteUserByUUID(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 }
 
@@ -0,0 +1,242 @@
+package handlerapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+
+	"go-server/internal/models"
+	"go-server/pkg/logging"
+)
+
+type AuctionHandler struct {
+	logger    *logging.Logger
+	validator *validator.Validate
+}
+
+func NewAuctionHandler() *AuctionHandler {
+	return &AuctionHandler{
+		logger:    logging.GetLogger(),
+		validator: validator.New(),
+	}
+}
+
+func (h *AuctionHandler) GetAuctionList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	query, err := parseAuctionQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	auctions, err := model.LoadAuctions(query)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidAuctionQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Errorf("failed to get auctions: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(auctions)
+}
+
+// parseAuctionQuery reads the filters of GET /api/auctions. Only active
+// auctions are returned unless another status is asked for.
+func parseAuctionQuery(values url.Values) (model.AuctionQuery, error) {
+	query := model.AuctionQuery{
+		Status: values.Get("status"),
+	}
+	if query.Status == "" {
+		query.Status = model.AuctionStatusActive
+	}
+
+	ids := map[string]*uuid.UUID{
+		"seller_id": &query.SellerID,
+		"item_id":   &query.ItemID,
+	}
+	for name, dst := range ids {
+		if v := values.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				return model.AuctionQuery{}, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*dst = id
+		}
+	}
+
+	return query, nil
+}
+
+func (h *AuctionHandler) GetAuctionByUUID(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	auctionID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse auctionID: %v", err)
+		http.Error(w, "Invalid AuctionID", http.StatusBadRequest)
+		return
+	}
+
+	auction, err := model.LoadAuctionByID(auctionID.String())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(auction)
+}
+
+func (h *AuctionHandler) CreateAuction(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	seller, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var auction *model.Auction
+	if err := json.NewDecoder(r.Body).Decode(&auction); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(auction); err != nil {
+		errors := err.(validator.ValidationErrors)
+		for _, e := range errors {
+			h.logger.Errorf("Validation error: %s", e)
+		}
+		http.Error(w, "Validation Error", http.StatusBadRequest)
+		return
+	}
+	auction.SellerID = seller.UserID
+
+	id, err := auction.Save()
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	created, err := model.LoadAuctionByID(id.String())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *AuctionHandler) GetAuctionBids(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	auctionID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse auctionID: %v", err)
+		http.Error(w, "Invalid AuctionID", http.StatusBadRequest)
+		return
+	}
+
+	bids, err := model.LoadAuctionBids(auctionID.String())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bids)
+}
+
+func (h *AuctionHandler) PlaceBid(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	auctionID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse auctionID: %v", err)
+		http.Error(w, "Invalid AuctionID", http.StatusBadRequest)
+		return
+	}
+
+	bidder, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var bid model.AuctionBid
+	if err := json.NewDecoder(r.Body).Decode(&bid); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(bid); err != nil {
+		errors := err.(validator.ValidationErrors)
+		for _, e := range errors {
+			h.logger.Errorf("Validation error: %s", e)
+		}
+		http.Error(w, "Validation Error", http.StatusBadRequest)
+		return
+	}
+
+	auction, err := model.PlaceAuctionBid(auctionID.String(), bidder, bid.Amount)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(auction)
+}
+
+func (h *AuctionHandler) CancelAuction(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	auctionID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse auctionID: %v", err)
+		http.Error(w, "Invalid AuctionID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	auction, err := model.CancelAuction(auctionID.String(), actor)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(auction)
+}
+
+func (h *AuctionHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, model.ErrAuctionNotFound):
+		http.Error(w, "Auction not found", http.StatusNotFound)
+	case errors.Is(err, model.ErrAuctionForbidden), errors.Is(err, model.ErrOwnAuctionBid):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, model.ErrInvalidAuction), errors.Is(err, model.ErrInvalidPrice):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.ErrBidTooLow):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, model.ErrInsufficientFunds):
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	case errors.Is(err, model.ErrAuctionNotActive), errors.Is(err, model.ErrAuctionHasBids),
+		errors.Is(err, model.ErrItemReserved), errors.Is(err, model.ErrItemNotOwned):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.logger.Errorf("failed to process auction: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
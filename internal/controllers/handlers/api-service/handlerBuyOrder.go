@@ -0,0 +1,203 @@
+package handlerapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+
+	"go-server/internal/models"
+	"go-server/pkg/logging"
+)
+
+type BuyOrderHandler struct {
+	logger    *logging.Logger
+	validator *validator.Validate
+}
+
+func NewBuyOrderHandler() *BuyOrderHandler {
+	return &BuyOrderHandler{
+		logger:    logging.GetLogger(),
+		validator: validator.New(),
+	}
+}
+
+func (h *BuyOrderHandler) GetOrderBook(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	itemID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse itemID: %v", err)
+		http.Error(w, "Invalid ItemID", http.StatusBadRequest)
+		return
+	}
+
+	book, err := model.LoadOrderBook(itemID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(book)
+}
+
+func (h *BuyOrderHandler) GetBuyOrderList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	query, err := parseBuyOrderQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	orders, err := model.LoadBuyOrders(query)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidBuyOrderQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Errorf("failed to get buy orders: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(orders)
+}
+
+// parseBuyOrderQuery reads the filters of GET /api/orders. Only active
+// orders are returned unless another status is asked for.
+func parseBuyOrderQuery(values url.Values) (model.BuyOrderQuery, error) {
+	query := model.BuyOrderQuery{
+		Status: values.Get("status"),
+	}
+	if query.Status == "" {
+		query.Status = model.BuyOrderStatusActive
+	}
+
+	ids := map[string]*uuid.UUID{
+		"buyer_id": &query.BuyerID,
+		"item_id":  &query.ItemID,
+	}
+	for name, dst := range ids {
+		if v := values.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				return model.BuyOrderQuery{}, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*dst = id
+		}
+	}
+
+	return query, nil
+}
+
+func (h *BuyOrderHandler) GetBuyOrderByUUID(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	orderID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse orderID: %v", err)
+		http.Error(w, "Invalid OrderID", http.StatusBadRequest)
+		return
+	}
+
+	order, err := model.LoadBuyOrderByID(orderID.String())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+// CreateBuyOrder places a buy order. The response shows the order already
+// filled when a matching listing was available.
+func (h *BuyOrderHandler) CreateBuyOrder(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	buyer, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var order *model.BuyOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(order); err != nil {
+		errors := err.(validator.ValidationErrors)
+		for _, e := range errors {
+			h.logger.Errorf("Validation error: %s", e)
+		}
+		http.Error(w, "Validation Error", http.StatusBadRequest)
+		return
+	}
+	order.BuyerID = buyer.UserID
+
+	id, err := order.Save()
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	created, err := model.LoadBuyOrderByID(id.String())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *BuyOrderHandler) CancelBuyOrder(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	orderID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse orderID: %v", err)
+		http.Error(w, "Invalid OrderID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	order, err := model.CancelBuyOrder(orderID.String(), actor)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *BuyOrderHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, model.ErrBuyOrderNotFound):
+		http.Error(w, "Buy order not found", http.StatusNotFound)
+	case errors.Is(err, model.ErrItemNotFound):
+		http.Error(w, "Item not found", http.StatusNotFound)
+	case errors.Is(err, model.ErrBuyOrderForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, model.ErrInvalidPrice):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.ErrInsufficientFunds):
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	case errors.Is(err, model.ErrBuyOrderNotActive):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.logger.Errorf("failed to process buy order: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -3,9 +3,14 @@ package handlerapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
 
 	itemgrpc "go-server/internal/clients/item/grpc"
@@ -72,6 +77,64 @@ func (h *ItemHandler) GetItemByUUID(w http.ResponseWriter, r *http.Request, para
 	json.NewEncoder(w).Encode(item)
 }
 
+func (h *ItemHandler) GetItemPrices(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	itemID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse itemID: %v", err)
+		http.Error(w, "Invalid ItemID", http.StatusBadRequest)
+		return
+	}
+
+	query, err := parsePriceHistoryQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	history, err := model.LoadPriceHistory(itemID, query)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrItemNotFound):
+			http.Error(w, "Item not found", http.StatusNotFound)
+		case errors.Is(err, model.ErrInvalidPriceHistoryQuery):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			h.logger.Errorf("failed to get price history: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
+// parsePriceHistoryQuery reads the filters of GET /api/items/:uuid/prices.
+// from and to are RFC 3339 timestamps.
+func parsePriceHistoryQuery(values url.Values) (model.PriceHistoryQuery, error) {
+	query := model.PriceHistoryQuery{
+		Interval: values.Get("interval"),
+		Currency: values.Get("currency"),
+	}
+
+	times := map[string]*time.Time{
+		"from": &query.From,
+		"to":   &query.To,
+	}
+	for name, dst := range times {
+		if v := values.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return model.PriceHistoryQuery{}, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*dst = t
+		}
+	}
+
+	return query, nil
+}
+
 func (h *ItemHandler) CreateItem(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	var newItem *model.Item
 
@@ -134,6 +197,13 @@ func (h *ItemHandler) UpdateItemDB(w http.ResponseWriter, r *http.Request, param
 		return
 	}
 
+	// Save market prices used to estimate the value of trades
+	if _, err := model.SaveItemPrices(&itemsResponse); err != nil {
+		h.logger.Errorf("failed to save item prices: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
 	// Create channel to manage goroutines
 	limit := make(chan struct{}, 100) // Ограничение до 100 одновременных запросов
 
@@ -0,0 +1,188 @@
+package handlerapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+
+	"go-server/internal/models"
+	"go-server/pkg/logging"
+)
+
+type ListingHandler struct {
+	logger    *logging.Logger
+	validator *validator.Validate
+}
+
+func NewListingHandler() *ListingHandler {
+	return &ListingHandler{
+		logger:    logging.GetLogger(),
+		validator: validator.New(),
+	}
+}
+
+func (h *ListingHandler) GetListingList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	query, err := parseListingQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	listings, err := model.LoadListings(query)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidListingQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Errorf("failed to get listings: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listings)
+}
+
+// parseListingQuery reads the filters of GET /api/listings. Only active
+// listings are returned unless another status is asked for.
+func parseListingQuery(values url.Values) (model.ListingQuery, error) {
+	query := model.ListingQuery{
+		Status: values.Get("status"),
+	}
+	if query.Status == "" {
+		query.Status = model.ListingStatusActive
+	}
+
+	ids := map[string]*uuid.UUID{
+		"seller_id": &query.SellerID,
+		"item_id":   &query.ItemID,
+	}
+	for name, dst := range ids {
+		if v := values.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				return model.ListingQuery{}, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*dst = id
+		}
+	}
+
+	return query, nil
+}
+
+func (h *ListingHandler) GetListingByUUID(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	listingID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse listingID: %v", err)
+		http.Error(w, "Invalid ListingID", http.StatusBadRequest)
+		return
+	}
+
+	listing, err := model.LoadListingByID(listingID.String())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listing)
+}
+
+func (h *ListingHandler) CreateListing(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	seller, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var listing *model.Listing
+	if err := json.NewDecoder(r.Body).Decode(&listing); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(listing); err != nil {
+		errors := err.(validator.ValidationErrors)
+		for _, e := range errors {
+			h.logger.Errorf("Validation error: %s", e)
+		}
+		http.Error(w, "Validation Error", http.StatusBadRequest)
+		return
+	}
+	listing.SellerID = seller.UserID
+
+	id, err := listing.Save()
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	created, err := model.LoadListingByID(id.String())
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *ListingHandler) BuyListing(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.closeListing(w, r, params, model.BuyListing)
+}
+
+func (h *ListingHandler) WithdrawListing(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.closeListing(w, r, params, model.WithdrawListing)
+}
+
+func (h *ListingHandler) closeListing(w http.ResponseWriter, r *http.Request, params httprouter.Params, close func(string, *model.Token) (*model.Listing, error)) {
+	listingID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse listingID: %v", err)
+		http.Error(w, "Invalid ListingID", http.StatusBadRequest)
+		return
+	}
+
+	actor, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	listing, err := close(listingID.String(), actor)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(listing)
+}
+
+func (h *ListingHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, model.ErrListingNotFound):
+		http.Error(w, "Listing not found", http.StatusNotFound)
+	case errors.Is(err, model.ErrListingForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, model.ErrInvalidPrice):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, model.ErrInsufficientFunds):
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	case errors.Is(err, model.ErrListingNotActive), errors.Is(err, model.ErrItemReserved), errors.Is(err, model.ErrItemNotOwned):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.logger.Errorf("failed to process listing: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -2,7 +2,13 @@ package handlerapi
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -26,12 +32,20 @@ func NewTradeHandler() *TradeHandler {
 }
 
 func (h *TradeHandler) CreateTrade(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	actor, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var newTrade *model.Trade
 
 	if err := json.NewDecoder(r.Body).Decode(&newTrade); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	newTrade.UserID = actor.UserID
+	newTrade.ActorID = actor.UserID
 
 	if err := h.validator.Struct(newTrade); err != nil {
 		errors := err.(validator.ValidationErrors)
@@ -44,20 +58,65 @@ func (h *TradeHandler) CreateTrade(w http.ResponseWriter, r *http.Request, param
 
 	id, err := newTrade.Save()
 	if err != nil {
-		h.logger.Fatalf("failed to create trade: %v", err)
+		if writeRuleViolations(w, err) {
+			return
+		}
+		if errors.Is(err, model.ErrInvalidTradeItem) || errors.Is(err, model.ErrInvalidTradeRecipient) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, model.ErrItemReserved) || errors.Is(err, model.ErrItemNotOwned) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		h.logger.Errorf("failed to create trade: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	created, err := model.LoadTradeByID(id.(uuid.UUID).String())
+	if err != nil {
+		h.logger.Errorf("failed to load created trade: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-	newTrade.TradeID = id.(uuid.UUID)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(newTrade)
+	json.NewEncoder(w).Encode(created)
+}
+
+// writeRuleViolations answers 422 with the trade rules err reports as
+// violated. It returns false if err is not a rule violation.
+func writeRuleViolations(w http.ResponseWriter, err error) bool {
+	var ruleErr *model.TradeRuleError
+	if !errors.As(err, &ruleErr) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":      model.ErrTradeRuleViolation.Error(),
+		"violations": ruleErr.Violations,
+	})
+	return true
 }
 
 func (h *TradeHandler) GetTradeList(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	trades, err := model.LoadTradeList()
+	query, err := parseTradeQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query.Viewer, _ = model.TokenFromContext(r.Context())
+
+	page, err := model.LoadTradePage(query)
 	if err != nil {
+		if errors.Is(err, model.ErrInvalidTradeQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		h.logger.Errorf("failed to get trades: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -65,7 +124,82 @@ func (h *TradeHandler) GetTradeList(w http.ResponseWriter, r *http.Request, para
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(trades)
+	json.NewEncoder(w).Encode(page)
+}
+
+// parseTradeQuery reads the filters of GET /api/trades. Dates are accepted as
+// RFC 3339 timestamps or as plain days; a plain created_to day is inclusive.
+func parseTradeQuery(values url.Values) (model.TradeQuery, error) {
+	query := model.TradeQuery{
+		Status: values.Get("status"),
+		Rarity: values.Get("rarity"),
+		Sort:   values.Get("sort"),
+		Cursor: values.Get("cursor"),
+	}
+
+	ids := map[string]*uuid.UUID{
+		"user_id":        &query.UserID,
+		"offered_item":   &query.OfferedItemID,
+		"requested_item": &query.RequestedItemID,
+	}
+	for name, dst := range ids {
+		if v := values.Get(name); v != "" {
+			id, err := uuid.Parse(v)
+			if err != nil {
+				return model.TradeQuery{}, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*dst = id
+		}
+	}
+
+	dates := map[string]**time.Time{
+		"created_from": &query.CreatedFrom,
+		"created_to":   &query.CreatedTo,
+	}
+	for name, dst := range dates {
+		v := values.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			day, dayErr := time.Parse(time.DateOnly, v)
+			if dayErr != nil {
+				return model.TradeQuery{}, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			t = day
+			if name == "created_to" {
+				t = t.AddDate(0, 0, 1)
+			}
+		}
+		*dst = &t
+	}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return model.TradeQuery{}, fmt.Errorf("invalid limit: %v", err)
+		}
+		query.Limit = limit
+	}
+
+	if v := values.Get("min_fairness"); v != "" {
+		minFairness, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return model.TradeQuery{}, fmt.Errorf("invalid min_fairness: %v", err)
+		}
+		query.MinFairness = &minFairness
+	}
+
+	if v := values.Get("min_reputation"); v != "" {
+		minReputation, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return model.TradeQuery{}, fmt.Errorf("invalid min_reputation: %v", err)
+		}
+		query.MinReputation = &minReputation
+	}
+
+	return query, nil
 }
 
 func (h *TradeHandler) GetTradesByItemUUID(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
@@ -85,6 +219,9 @@ func (h *TradeHandler) GetTradesByItemUUID(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	viewer, _ := model.TokenFromContext(r.Context())
+	trades = model.VisibleTrades(trades, viewer)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(trades)
@@ -100,7 +237,25 @@ func (h *TradeHandler) DeleteTradeByUUID(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	if err := model.DeleteTradeByID(tradeID.String()); err != nil {
+	actor, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := model.DeleteTradeByID(tradeID.String(), actor); err != nil {
+		if errors.Is(err, model.ErrTradeNotFound) {
+			http.Error(w, "Trade not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, model.ErrTradeForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, model.ErrTradeInProgress) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		h.logger.Errorf("failed to delete trade by ID: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
@@ -126,19 +281,43 @@ func (h *TradeHandler) GetTradeByTradeUUID(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if trade.TradeID == uuid.Nil {
+	viewer, _ := model.TokenFromContext(r.Context())
+	if trade.TradeID == uuid.Nil || !trade.VisibleTo(viewer) {
 		h.logger.Errorf("trade with ID %s not found", tradeID)
 		http.Error(w, "Trade not found", http.StatusNotFound)
 		return
 	}
 
+	w.Header().Set("ETag", tradeETag(trade.Version))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(trade)
 }
 
 func (h *TradeHandler) UpdateTradeByUUID(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-	tradeID := params.ByName("uuid")
+	actor, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tradeID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse tradeID: %v", err)
+		http.Error(w, "Invalid TradeID", http.StatusBadRequest)
+		return
+	}
+
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		http.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+		return
+	}
+	version, err := parseTradeETag(ifMatch)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	var updateData *model.Trade
 	if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
@@ -146,21 +325,44 @@ func (h *TradeHandler) UpdateTradeByUUID(w http.ResponseWriter, r *http.Request,
 		http.Error(w, "Bad Request", http.StatusBadRequest)
 		return
 	}
+	updateData.TradeID = tradeID
+	updateData.Version = version
 
-	_, err := updateData.Save()
+	err = model.UpdateTrade(updateData, actor)
 	if err != nil {
+		if writeRuleViolations(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, model.ErrTradeNotFound):
+			http.Error(w, "Trade not found", http.StatusNotFound)
+			return
+		case errors.Is(err, model.ErrTradeForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		case errors.Is(err, model.ErrTradeVersionConflict):
+			http.Error(w, err.Error(), http.StatusPreconditionFailed)
+			return
+		case errors.Is(err, model.ErrInvalidTradeItem):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, model.ErrTradeNotPending), errors.Is(err, model.ErrAuctionTrade), errors.Is(err, model.ErrItemReserved), errors.Is(err, model.ErrItemNotOwned):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		h.logger.Errorf("failed to update trade by UUID: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	updatedTrade, err := model.LoadTradeByID(tradeID)
+	updatedTrade, err := model.LoadTradeByID(tradeID.String())
 	if err != nil {
 		h.logger.Errorf("failed to get trade by ID: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("ETag", tradeETag(updatedTrade.Version))
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(updatedTrade)
@@ -183,7 +385,455 @@ func (h *TradeHandler) GetTradesByUserUUID(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	viewer, _ := model.TokenFromContext(r.Context())
+	trades = model.VisibleTrades(trades, viewer)
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(trades)
 }
+
+// GetIncomingTrades lists the private trades addressed to the user. Only the
+// user and admins may see them.
+func (h *TradeHandler) GetIncomingTrades(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.getPrivateTrades(w, r, params, model.LoadIncomingTrades)
+}
+
+// GetOutgoingTrades lists the private trades the user sent. Only the user and
+// admins may see them.
+func (h *TradeHandler) GetOutgoingTrades(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.getPrivateTrades(w, r, params, model.LoadOutgoingTrades)
+}
+
+func (h *TradeHandler) getPrivateTrades(w http.ResponseWriter, r *http.Request, params httprouter.Params, load func(string) ([]*model.Trade, error)) {
+	userID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse userID: %v", err)
+		http.Error(w, "Invalid UserID", http.StatusBadRequest)
+		return
+	}
+
+	viewer, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if viewer.UserRole != "admin" && viewer.UserID != userID {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return
+	}
+
+	trades, err := load(userID.String())
+	if err != nil {
+		h.logger.Errorf("failed to get private trades: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(trades)
+}
+
+func (h *TradeHandler) CreateCounterOffer(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	actor, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	parentID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse tradeID: %v", err)
+		http.Error(w, "Invalid TradeID", http.StatusBadRequest)
+		return
+	}
+
+	var counter *model.Trade
+	if err := json.NewDecoder(r.Body).Decode(&counter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	counter.UserID = actor.UserID
+	counter.ActorID = actor.UserID
+
+	if err := h.validator.Struct(counter); err != nil {
+		errors := err.(validator.ValidationErrors)
+		for _, e := range errors {
+			h.logger.Errorf("Validation error: %s", e)
+		}
+		http.Error(w, "Validation Error", http.StatusBadRequest)
+		return
+	}
+
+	id, err := model.CreateCounterOffer(parentID.String(), counter)
+	if err != nil {
+		if writeRuleViolations(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, model.ErrTradeNotFound):
+			http.Error(w, "Trade not found", http.StatusNotFound)
+		case errors.Is(err, model.ErrInvalidTradeItem):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, model.ErrTradeForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, model.ErrTradeNotPending), errors.Is(err, model.ErrAuctionTrade), errors.Is(err, model.ErrItemReserved), errors.Is(err, model.ErrItemNotOwned):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			h.logger.Errorf("failed to create counter-offer: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	created, err := model.LoadTradeByID(id.String())
+	if err != nil {
+		h.logger.Errorf("failed to load created counter-offer: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *TradeHandler) GetNegotiation(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	tradeID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse tradeID: %v", err)
+		http.Error(w, "Invalid TradeID", http.StatusBadRequest)
+		return
+	}
+
+	trades, err := model.LoadNegotiation(tradeID.String())
+	if err != nil {
+		h.logger.Errorf("failed to get negotiation thread: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	viewer, _ := model.TokenFromContext(r.Context())
+	trades = model.VisibleTrades(trades, viewer)
+
+	if len(trades) == 0 {
+		http.Error(w, "Trade not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(trades)
+}
+
+func (h *TradeHandler) GetTradeMatches(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	tradeID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse tradeID: %v", err)
+		http.Error(w, "Invalid TradeID", http.StatusBadRequest)
+		return
+	}
+
+	viewer, _ := model.TokenFromContext(r.Context())
+	matches, err := model.FindTradeMatches(tradeID.String(), viewer)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrTradeNotFound):
+			http.Error(w, "Trade not found", http.StatusNotFound)
+		case errors.Is(err, model.ErrTradeNotPending):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			h.logger.Errorf("failed to find trade matches: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(matches)
+}
+
+func (h *TradeHandler) GetTradeHistory(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	tradeID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse tradeID: %v", err)
+		http.Error(w, "Invalid TradeID", http.StatusBadRequest)
+		return
+	}
+
+	viewer, _ := model.TokenFromContext(r.Context())
+	events, err := model.LoadTradeHistory(tradeID.String(), viewer)
+	if err != nil {
+		if errors.Is(err, model.ErrTradeNotFound) {
+			http.Error(w, "Trade not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Errorf("failed to get trade history: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if len(events) == 0 {
+		http.Error(w, "Trade not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+}
+
+// GetTradeReceipt returns the signed receipt of a completed trade.
+func (h *TradeHandler) GetTradeReceipt(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	tradeID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse tradeID: %v", err)
+		http.Error(w, "Invalid TradeID", http.StatusBadRequest)
+		return
+	}
+
+	viewer, _ := model.TokenFromContext(r.Context())
+	receipt, err := model.LoadTradeReceipt(tradeID.String(), viewer)
+	if err != nil {
+		if errors.Is(err, model.ErrReceiptNotFound) {
+			http.Error(w, "Receipt not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Errorf("failed to get trade receipt: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(receipt)
+}
+
+// RateTrade lets a party of a completed trade rate the other party once.
+func (h *TradeHandler) RateTrade(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	actor, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tradeID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse tradeID: %v", err)
+		http.Error(w, "Invalid TradeID", http.StatusBadRequest)
+		return
+	}
+
+	var rating model.NewRating
+	if err := json.NewDecoder(r.Body).Decode(&rating); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(rating); err != nil {
+		errors := err.(validator.ValidationErrors)
+		http.Error(w, fmt.Sprintf("Validation error: %s", errors), http.StatusBadRequest)
+		return
+	}
+
+	created, err := model.RateTrade(tradeID.String(), rating, actor)
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrTradeNotFound):
+			http.Error(w, "Trade not found", http.StatusNotFound)
+		case errors.Is(err, model.ErrNotTradeParty):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, model.ErrTradeNotCompleted), errors.Is(err, model.ErrTradeAlreadyRated):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			h.logger.Errorf("failed to rate trade: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GetReceiptPublicKey returns the key that verifies trade receipts.
+func (h *TradeHandler) GetReceiptPublicKey(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	key, err := model.LoadReceiptPublicKey()
+	if err != nil {
+		if errors.Is(err, model.ErrNoSigningKey) {
+			http.Error(w, "Receipts are not signed", http.StatusServiceUnavailable)
+			return
+		}
+		h.logger.Errorf("failed to get receipt public key: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(key)
+}
+
+func (h *TradeHandler) GetTradeMessages(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	tradeID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse tradeID: %v", err)
+		http.Error(w, "Invalid TradeID", http.StatusBadRequest)
+		return
+	}
+
+	viewer, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, fmt.Sprintf("invalid limit: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := model.LoadTradeMessages(tradeID.String(), viewer, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		h.writeMessageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(page)
+}
+
+func (h *TradeHandler) PostTradeMessage(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	tradeID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse tradeID: %v", err)
+		http.Error(w, "Invalid TradeID", http.StatusBadRequest)
+		return
+	}
+
+	sender, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var message *model.TradeMessage
+	if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(message); err != nil {
+		errors := err.(validator.ValidationErrors)
+		for _, e := range errors {
+			h.logger.Errorf("Validation error: %s", e)
+		}
+		http.Error(w, "Validation Error", http.StatusBadRequest)
+		return
+	}
+
+	posted, err := model.PostTradeMessage(tradeID.String(), sender, message)
+	if err != nil {
+		h.writeMessageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(posted)
+}
+
+func (h *TradeHandler) writeMessageError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, model.ErrTradeNotFound):
+		http.Error(w, "Trade not found", http.StatusNotFound)
+	case errors.Is(err, model.ErrTradeForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, model.ErrInvalidMessageQuery):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		h.logger.Errorf("failed to process trade messages: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+func (h *TradeHandler) AcceptTrade(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.changeTradeStatus(w, r, params, model.AcceptTrade)
+}
+
+func (h *TradeHandler) RejectTrade(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.changeTradeStatus(w, r, params, model.RejectTrade)
+}
+
+func (h *TradeHandler) CancelTrade(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.changeTradeStatus(w, r, params, model.CancelTrade)
+}
+
+func (h *TradeHandler) CompleteTrade(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	h.changeTradeStatus(w, r, params, model.CompleteTrade)
+}
+
+func (h *TradeHandler) changeTradeStatus(w http.ResponseWriter, r *http.Request, params httprouter.Params, change func(string, *model.Token) (*model.Trade, error)) {
+	actor, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tradeID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("failed to parse tradeID: %v", err)
+		http.Error(w, "Invalid TradeID", http.StatusBadRequest)
+		return
+	}
+
+	trade, err := change(tradeID.String(), actor)
+	if err != nil {
+		if writeRuleViolations(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, model.ErrTradeNotFound):
+			http.Error(w, "Trade not found", http.StatusNotFound)
+		case errors.Is(err, model.ErrTradeForbidden):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, model.ErrInvalidTradeTransition), errors.Is(err, model.ErrItemNotOwned), errors.Is(err, model.ErrItemReserved), errors.Is(err, model.ErrTradeUnderReview):
+			h.logger.Infof("rejected trade status change: %v", err)
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			h.logger.Errorf("failed to change trade status: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("ETag", tradeETag(trade.Version))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(trade)
+}
+
+// tradeETag renders a trade version as a strong entity tag.
+func tradeETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// parseTradeETag reads the version out of an If-Match header. Only a single
+// strong entity tag as rendered by tradeETag is accepted: "*" and weak tags
+// would let a client overwrite a trade without knowing its version.
+func parseTradeETag(header string) (int, error) {
+	header = strings.TrimSpace(header)
+
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version <= 0 || tradeETag(version) != header {
+		return 0, fmt.Errorf("invalid If-Match header %q, want a strong entity tag like %s", header, tradeETag(1))
+	}
+	return version, nil
+}
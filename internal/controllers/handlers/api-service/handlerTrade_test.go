@@ -0,0 +1,52 @@
+package handlerapi
+
+import "testing"
+
+func TestParseTradeETag(t *testing.T) {
+	tests := []struct {
+		header  string
+		want    int
+		wantErr bool
+	}{
+		{`"1"`, 1, false},
+		{`"42"`, 42, false},
+		{` "7" `, 7, false},
+		{`*`, 0, true},
+		{`W/"3"`, 0, true},
+		{`3`, 0, true},
+		{`"0"`, 0, true},
+		{`"-1"`, 0, true},
+		{`"+3"`, 0, true},
+		{`"03"`, 0, true},
+		{`"3`, 0, true},
+		{`"3", "4"`, 0, true},
+		{``, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			got, err := parseTradeETag(tt.header)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got version %d", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got version %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTradeETagRoundTrip(t *testing.T) {
+	for _, version := range []int{1, 9, 10, 123456} {
+		got, err := parseTradeETag(tradeETag(version))
+		if err != nil || got != version {
+			t.Errorf("parseTradeETag(tradeETag(%d)) = %d, %v", version, got, err)
+		}
+	}
+}
@@ -2,8 +2,10 @@ package handlerapi
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
@@ -49,7 +51,7 @@ func (h *UserHandler) GetUserList(w http.ResponseWriter, r *http.Request, params
 func (h *UserHandler) GetUserByUUID(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
 	userID := params.ByName("uuid")
 
-	user, err := model.LoadUser(userID)
+	user, err := model.LoadUserProfile(userID)
 	if err != nil {
 		h.logger.Errorf("ошибка при получении пользователя по UUID: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
@@ -143,3 +145,279 @@ func (h *UserHandler) UpdateUserByUUID(w http.ResponseWriter, r *http.Request, p
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(updatedUser)
 }
+
+func (h *UserHandler) GetUserInventory(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	userID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("ошибка при парсинге UUID пользователя: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	items, err := model.LoadInventory(userID.String())
+	if err != nil {
+		h.logger.Errorf("ошибка при получении инвентаря пользователя: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(items)
+}
+
+// authorizeUser checks that the request is made by the user itself or by an
+// admin and writes the error response otherwise.
+func (h *UserHandler) authorizeUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	actor, ok := model.TokenFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if actor.UserRole != "admin" && actor.UserID != userID {
+		http.Error(w, "Access denied", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (h *UserHandler) GetUserWishlist(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	userID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("ошибка при парсинге UUID пользователя: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeUser(w, r, userID) {
+		return
+	}
+
+	entries, err := model.LoadWishlist(userID.String())
+	if err != nil {
+		h.logger.Errorf("ошибка при получении списка желаемого: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(entries)
+}
+
+func (h *UserHandler) AddWishlistItem(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	userID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("ошибка при парсинге UUID пользователя: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeUser(w, r, userID) {
+		return
+	}
+
+	var entry model.WishlistEntry
+	if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+		h.logger.Errorf("ошибка при декодировании JSON: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validator.Struct(entry); err != nil {
+		h.logger.Errorf("ошибка валидации: %v", err)
+		http.Error(w, "Validation Error", http.StatusBadRequest)
+		return
+	}
+	entry.UserID = userID
+
+	if err := entry.Save(); err != nil {
+		switch {
+		case errors.Is(err, model.ErrInvalidWishlistEntry):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, model.ErrItemNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			h.logger.Errorf("ошибка при добавлении предмета в список желаемого: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+func (h *UserHandler) DeleteWishlistItem(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	userID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("ошибка при парсинге UUID пользователя: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	itemID, err := uuid.Parse(params.ByName("item"))
+	if err != nil {
+		h.logger.Errorf("ошибка при парсинге UUID предмета: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeUser(w, r, userID) {
+		return
+	}
+
+	if err := model.RemoveWishlistEntry(userID.String(), itemID.String()); err != nil {
+		if errors.Is(err, model.ErrWishlistEntryNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		h.logger.Errorf("ошибка при удалении предмета из списка желаемого: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) GetUserNotifications(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	userID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("ошибка при парсинге UUID пользователя: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeUser(w, r, userID) {
+		return
+	}
+
+	query := r.URL.Query()
+	unreadOnly := query.Get("unread") == "true"
+
+	var limit int
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, fmt.Sprintf("invalid limit: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	notifications, err := model.LoadNotifications(userID.String(), unreadOnly, limit)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidNotificationQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Errorf("ошибка при получении уведомлений: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notifications)
+}
+
+func (h *UserHandler) ReadNotification(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	userID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("ошибка при парсинге UUID пользователя: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	notificationID, err := uuid.Parse(params.ByName("notification"))
+	if err != nil {
+		h.logger.Errorf("ошибка при парсинге UUID уведомления: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeUser(w, r, userID) {
+		return
+	}
+
+	if err := model.MarkNotificationRead(userID.String(), notificationID.String()); err != nil {
+		if errors.Is(err, model.ErrNotificationNotFound) {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		h.logger.Errorf("ошибка при отметке уведомления как прочитанного: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUserRatings returns the latest ratings the user received from trade
+// partners.
+func (h *UserHandler) GetUserRatings(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	userID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("ошибка при парсинге UUID пользователя: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, fmt.Sprintf("invalid limit: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ratings, err := model.LoadUserRatings(userID, limit)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidRatingQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Errorf("ошибка при получении оценок пользователя: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ratings)
+}
+
+func (h *UserHandler) GetUserWallet(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+	userID, err := uuid.Parse(params.ByName("uuid"))
+	if err != nil {
+		h.logger.Errorf("ошибка при парсинге UUID пользователя: %v", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeUser(w, r, userID) {
+		return
+	}
+
+	var limit int
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			http.Error(w, fmt.Sprintf("invalid limit: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	wallet, err := model.LoadWallet(userID, limit)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidWalletQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Errorf("ошибка при получении кошелька: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(wallet)
+}
@@ -33,6 +33,8 @@ func AuthMiddleware(next httprouter.Handle, logger *logging.Logger) httprouter.H
 			return
 		}
 
+		r = r.WithContext(model.ContextWithToken(r.Context(), claims))
+
 		if claims.UserRole == "admin" {
 			next(w, r, params)
 			return
@@ -50,8 +52,22 @@ func AuthMiddleware(next httprouter.Handle, logger *logging.Logger) httprouter.H
 	}
 }
 
+// OptionalAuthMiddleware lets anonymous requests through. When an
+// Authorization header is sent it is checked like in AuthMiddleware and the
+// token is stored in the request context.
+func OptionalAuthMiddleware(next httprouter.Handle, logger *logging.Logger) httprouter.Handle {
+	auth := AuthMiddleware(next, logger)
+	return func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
+		if r.Header.Get("Authorization") == "" {
+			next(w, r, params)
+			return
+		}
+		auth(w, r, params)
+	}
+}
+
 func isPathForAdmin(path string) bool {
-	adminURLs := []string{"/api/admin/users"}
+	adminURLs := []string{"/api/admin/users", "/api/admin/stats", "/api/admin/reviews"}
 
 	for _, url := range adminURLs {
 		if path == url || strings.HasPrefix(path, url+"/") {
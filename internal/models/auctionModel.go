@@ -0,0 +1,332 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/config"
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+const (
+	AuctionStatusActive    = "active"
+	AuctionStatusEnded     = "ended"
+	AuctionStatusCancelled = "cancelled"
+)
+
+var (
+	ErrAuctionNotFound     = db.ErrAuctionNotFound
+	ErrAuctionNotActive    = db.ErrAuctionNotActive
+	ErrAuctionHasBids      = db.ErrAuctionHasBids
+	ErrBidTooLow           = db.ErrBidTooLow
+	ErrOwnAuctionBid       = db.ErrOwnAuctionBid
+	ErrAuctionTrade        = db.ErrAuctionTrade
+	ErrAuctionForbidden    = errors.New("operation is not allowed for this user")
+	ErrInvalidAuction      = errors.New("invalid auction")
+	ErrInvalidAuctionQuery = errors.New("invalid auction query")
+)
+
+// Auction is a timed sale of a single item to the highest bidder. The
+// auctioned inventory instance stays in escrow until the auction is closed;
+// the winner then receives a private trade offering the item.
+type Auction struct {
+	AuctionID    uuid.UUID  `json:"auction_id"`
+	SellerID     uuid.UUID  `json:"seller_id"`
+	ItemID       uuid.UUID  `json:"item_id" validate:"required"`
+	Name         string     `json:"name"`
+	Rarity       string     `json:"rarity"`
+	Quality      string     `json:"quality,omitempty"`
+	StartPrice   float64    `json:"start_price" validate:"required,gte=0.01"`
+	MinIncrement float64    `json:"min_increment" validate:"required,gte=0.01"`
+	Currency     string     `json:"currency" validate:"omitempty,min=3,max=10"`
+	Status       string     `json:"status"`
+	CurrentBid   *float64   `json:"current_bid,omitempty"`
+	HighBidderID *uuid.UUID `json:"high_bidder_id,omitempty"`
+	BidCount     int        `json:"bid_count"`
+	EndsAt       time.Time  `json:"ends_at" validate:"required"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+	TradeID      *uuid.UUID `json:"trade_id,omitempty"` // trade offered to the winner
+}
+
+type AuctionBid struct {
+	BidID     uuid.UUID `json:"bid_id"`
+	AuctionID uuid.UUID `json:"auction_id"`
+	BidderID  uuid.UUID `json:"bidder_id"`
+	Amount    float64   `json:"amount" validate:"required,gte=0.01"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuctionQuery filters a listing of auctions. Empty fields match everything.
+type AuctionQuery struct {
+	Status   string
+	SellerID uuid.UUID
+	ItemID   uuid.UUID
+}
+
+func (a *Auction) Save() (uuid.UUID, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryAuction(logger)
+
+	if repo == nil {
+		return uuid.Nil, fmt.Errorf("failed to create repository")
+	}
+
+	now := time.Now()
+	if !a.EndsAt.After(now) {
+		return uuid.Nil, fmt.Errorf("%w: ends_at must be in the future", ErrInvalidAuction)
+	}
+	if maxDuration := config.GetConfig().Auctions.MaxDuration; a.EndsAt.After(now.Add(maxDuration)) {
+		return uuid.Nil, fmt.Errorf("%w: auction cannot last longer than %s", ErrInvalidAuction, maxDuration)
+	}
+	if err := checkAuctionRarity(a.ItemID); err != nil {
+		return uuid.Nil, err
+	}
+
+	id, err := repo.Create(context.TODO(), db.AuctionData{
+		SellerID:     a.SellerID,
+		ItemID:       a.ItemID,
+		StartPrice:   a.StartPrice,
+		MinIncrement: a.MinIncrement,
+		Currency:     a.Currency,
+		EndsAt:       a.EndsAt,
+	}, config.GetConfig().Market.DefaultCurrency)
+	if err != nil {
+		logger.Infof("Failed to create auction: %v", err)
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// checkAuctionRarity keeps items more common than auctions.min_rarity out of
+// auctions.
+func checkAuctionRarity(itemID uuid.UUID) error {
+	repo := db.NewRepositoryItem(logging.GetLogger())
+	if repo == nil {
+		return fmt.Errorf("failed to create repository")
+	}
+
+	items, err := repo.FindByIDs(context.TODO(), []uuid.UUID{itemID})
+	if err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("%w: unknown item %s", ErrInvalidAuction, itemID)
+	}
+
+	minRarity := strings.ToLower(config.GetConfig().Auctions.MinRarity)
+	rarity := strings.ToLower(items[0].Rarity)
+	if slices.Index(db.ItemRarities, rarity) < slices.Index(db.ItemRarities, minRarity) {
+		return fmt.Errorf("%w: %s items cannot be auctioned, the rarity must be at least %s", ErrInvalidAuction, items[0].Rarity, minRarity)
+	}
+	return nil
+}
+
+func LoadAuctions(query AuctionQuery) ([]*Auction, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryAuction(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	switch query.Status {
+	case "", AuctionStatusActive, AuctionStatusEnded, AuctionStatusCancelled:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidAuctionQuery, query.Status)
+	}
+
+	data, err := repo.FindAll(context.TODO(), db.AuctionFilter{
+		Status:   query.Status,
+		SellerID: query.SellerID,
+		ItemID:   query.ItemID,
+	})
+	if err != nil {
+		logger.Infof("Failed to load auctions: %v", err)
+		return []*Auction{}, err
+	}
+
+	auctions := make([]*Auction, 0, len(data))
+	for _, a := range data {
+		auctions = append(auctions, newAuctionFromData(a))
+	}
+	return auctions, nil
+}
+
+func LoadAuctionByID(auctionID string) (*Auction, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryAuction(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	data, err := repo.FindOne(context.TODO(), auctionID)
+	if err != nil {
+		logger.Infof("Failed to load auction by ID: %v", err)
+		return nil, err
+	}
+	return newAuctionFromData(data), nil
+}
+
+func LoadAuctionBids(auctionID string) ([]*AuctionBid, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryAuction(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	auction, err := LoadAuctionByID(auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := repo.FindBids(context.TODO(), auction.AuctionID)
+	if err != nil {
+		logger.Infof("Failed to load auction bids: %v", err)
+		return nil, err
+	}
+
+	bids := make([]*AuctionBid, 0, len(data))
+	for _, b := range data {
+		bids = append(bids, &AuctionBid{
+			BidID:     b.ID,
+			AuctionID: b.AuctionID,
+			BidderID:  b.BidderID,
+			Amount:    b.Amount,
+			CreatedAt: b.CreatedAt,
+		})
+	}
+	return bids, nil
+}
+
+// PlaceAuctionBid bids amount on behalf of bidder. Bids placed in the last
+// minutes of an auction extend it, see config.AuctionsConfig.
+func PlaceAuctionBid(auctionID string, bidder *Token, amount float64) (*Auction, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryAuction(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	auction, err := LoadAuctionByID(auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := config.GetConfig().Auctions
+	if err := repo.PlaceBid(context.TODO(), auction.AuctionID, bidder.UserID, amount, cfg.SnipingWindow, cfg.SnipingExtension); err != nil {
+		logger.Infof("Failed to place bid: %v", err)
+		return nil, err
+	}
+
+	return LoadAuctionByID(auctionID)
+}
+
+// CancelAuction cancels an active auction without bids. Only the seller and
+// admins may cancel an auction.
+func CancelAuction(auctionID string, actor *Token) (*Auction, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryAuction(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	auction, err := LoadAuctionByID(auctionID)
+	if err != nil {
+		return nil, err
+	}
+	if actor.UserRole != "admin" && auction.SellerID != actor.UserID {
+		return nil, fmt.Errorf("%w: only the seller can cancel an auction", ErrAuctionForbidden)
+	}
+
+	if err := repo.Cancel(context.TODO(), auction.AuctionID); err != nil {
+		logger.Infof("Failed to cancel auction: %v", err)
+		return nil, err
+	}
+
+	return LoadAuctionByID(auctionID)
+}
+
+// CloseEndedAuctions closes every active auction whose end time has passed
+// and offers the item to the winner through a pending trade. Auctions are
+// processed in batches like ExpireStaleTrades.
+func CloseEndedAuctions() {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryAuction(logger)
+
+	if repo == nil {
+		logger.Fatal("failed to create repository")
+	}
+
+	cfg := config.GetConfig()
+	batchSize := cfg.Auctions.CloseBatchSize
+	total := 0
+
+	for {
+		ids, err := repo.FindEnded(context.TODO(), batchSize)
+		if err != nil {
+			logger.Errorf("Error loading ended auctions: %v", err)
+			break
+		}
+
+		closed := 0
+		for _, id := range ids {
+			tradeID, ok, err := repo.Close(context.TODO(), id, time.Now().Add(cfg.Trades.TTL))
+			if err != nil {
+				logger.Errorf("Error closing auction %s: %v", id, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			closed++
+			if tradeID != nil {
+				logger.Infof("Auction %s ended, trade %s created for the winner", id, tradeID)
+			}
+		}
+		total += closed
+
+		// Stop when the batch was the last one or nothing could be closed, so
+		// a failing auction does not keep the loop spinning.
+		if len(ids) < batchSize || closed == 0 {
+			break
+		}
+	}
+
+	if total > 0 {
+		logger.Infof("Closed %d ended auctions", total)
+	}
+}
+
+func newAuctionFromData(data db.AuctionData) *Auction {
+	return &Auction{
+		AuctionID:    data.ID,
+		SellerID:     data.SellerID,
+		ItemID:       data.ItemID,
+		Name:         data.Name,
+		Rarity:       data.Rarity,
+		Quality:      data.Quality,
+		StartPrice:   data.StartPrice,
+		MinIncrement: data.MinIncrement,
+		Currency:     data.Currency,
+		Status:       data.Status,
+		CurrentBid:   data.CurrentBid,
+		HighBidderID: data.HighBidderID,
+		BidCount:     data.BidCount,
+		EndsAt:       data.EndsAt,
+		CreatedAt:    data.CreatedAt,
+		ClosedAt:     data.ClosedAt,
+		TradeID:      data.TradeID,
+	}
+}
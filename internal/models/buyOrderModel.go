@@ -0,0 +1,187 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/config"
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+const (
+	BuyOrderStatusActive    = "active"
+	BuyOrderStatusFilled    = "filled"
+	BuyOrderStatusCancelled = "cancelled"
+)
+
+var (
+	ErrBuyOrderNotFound     = db.ErrBuyOrderNotFound
+	ErrBuyOrderNotActive    = db.ErrBuyOrderNotActive
+	ErrBuyOrderForbidden    = errors.New("operation is not allowed for this user")
+	ErrInvalidBuyOrderQuery = errors.New("invalid buy order query")
+)
+
+// BuyOrder is a standing offer to buy an item at up to Price. It is filled
+// by the first listing of the item at or below that price.
+type BuyOrder struct {
+	OrderID   uuid.UUID  `json:"order_id"`
+	BuyerID   uuid.UUID  `json:"buyer_id"`
+	ItemID    uuid.UUID  `json:"item_id" validate:"required"`
+	Name      string     `json:"name"`
+	Rarity    string     `json:"rarity"`
+	Price     float64    `json:"price" validate:"required,gte=0.01"`
+	Currency  string     `json:"currency" validate:"omitempty,min=3,max=10"`
+	Status    string     `json:"status"`
+	ListingID *uuid.UUID `json:"listing_id,omitempty"` // listing bought when the order was filled
+	FillPrice *float64   `json:"fill_price,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+}
+
+// BuyOrderQuery filters a listing of buy orders. Empty fields match everything.
+type BuyOrderQuery struct {
+	Status  string
+	BuyerID uuid.UUID
+	ItemID  uuid.UUID
+}
+
+// OrderBook shows the open demand (bids) and supply (asks) of an item. Bids
+// come from buy orders, asks from active listings.
+type OrderBook struct {
+	ItemID uuid.UUID           `json:"item_id"`
+	Bids   []db.OrderBookLevel `json:"bids"`
+	Asks   []db.OrderBookLevel `json:"asks"`
+}
+
+func (o *BuyOrder) Save() (uuid.UUID, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryBuyOrder(logger)
+
+	if repo == nil {
+		return uuid.Nil, fmt.Errorf("failed to create repository")
+	}
+
+	id, err := repo.Create(context.TODO(), db.BuyOrderData{
+		BuyerID:  o.BuyerID,
+		ItemID:   o.ItemID,
+		Price:    o.Price,
+		Currency: o.Currency,
+	}, config.GetConfig().Market.DefaultCurrency)
+	if err != nil {
+		logger.Infof("Failed to create buy order: %v", err)
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+func LoadBuyOrders(query BuyOrderQuery) ([]*BuyOrder, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryBuyOrder(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	switch query.Status {
+	case "", BuyOrderStatusActive, BuyOrderStatusFilled, BuyOrderStatusCancelled:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidBuyOrderQuery, query.Status)
+	}
+
+	data, err := repo.FindAll(context.TODO(), db.BuyOrderFilter{
+		Status:  query.Status,
+		BuyerID: query.BuyerID,
+		ItemID:  query.ItemID,
+	})
+	if err != nil {
+		logger.Infof("Failed to load buy orders: %v", err)
+		return []*BuyOrder{}, err
+	}
+
+	orders := make([]*BuyOrder, 0, len(data))
+	for _, o := range data {
+		orders = append(orders, newBuyOrderFromData(o))
+	}
+	return orders, nil
+}
+
+func LoadBuyOrderByID(orderID string) (*BuyOrder, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryBuyOrder(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	data, err := repo.FindOne(context.TODO(), orderID)
+	if err != nil {
+		logger.Infof("Failed to load buy order by ID: %v", err)
+		return nil, err
+	}
+	return newBuyOrderFromData(data), nil
+}
+
+// CancelBuyOrder closes an active buy order. Only the buyer and admins may
+// cancel an order.
+func CancelBuyOrder(orderID string, actor *Token) (*BuyOrder, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryBuyOrder(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	order, err := LoadBuyOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if actor.UserRole != "admin" && order.BuyerID != actor.UserID {
+		return nil, fmt.Errorf("%w: only the buyer can cancel a buy order", ErrBuyOrderForbidden)
+	}
+
+	if err := repo.Cancel(context.TODO(), order.OrderID); err != nil {
+		logger.Infof("Failed to cancel buy order: %v", err)
+		return nil, err
+	}
+
+	return LoadBuyOrderByID(orderID)
+}
+
+func LoadOrderBook(itemID uuid.UUID) (*OrderBook, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryBuyOrder(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	bids, asks, err := repo.FindOrderBook(context.TODO(), itemID)
+	if err != nil {
+		logger.Infof("Failed to load order book: %v", err)
+		return nil, err
+	}
+
+	return &OrderBook{ItemID: itemID, Bids: bids, Asks: asks}, nil
+}
+
+func newBuyOrderFromData(data db.BuyOrderData) *BuyOrder {
+	return &BuyOrder{
+		OrderID:   data.ID,
+		BuyerID:   data.BuyerID,
+		ItemID:    data.ItemID,
+		Name:      data.Name,
+		Rarity:    data.Rarity,
+		Price:     data.Price,
+		Currency:  data.Currency,
+		Status:    data.Status,
+		ListingID: data.ListingID,
+		FillPrice: data.FillPrice,
+		CreatedAt: data.CreatedAt,
+		ClosedAt:  data.ClosedAt,
+	}
+}
@@ -0,0 +1,266 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/config"
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+// Fraud check names as declared in the fraud section of config.yaml.
+const (
+	FraudCheckLopsidedValue   = config.FraudCheckLopsidedValue
+	FraudCheckNewAccountBurst = config.FraudCheckNewAccountBurst
+	FraudCheckPingPong        = config.FraudCheckPingPong
+	FraudCheckSharedUserAgent = config.FraudCheckSharedUserAgent
+)
+
+// fraudCheck looks for one suspicious pattern in a trade. It returns nil if
+// the trade does not show it.
+type fraudCheck func(c *fraudContext) (*db.FraudSignal, error)
+
+// fraudContext is the trade being scored and the user acting on it: the
+// creator when the trade is created, the accepting user when it is accepted.
+// counterpartyID is the other party, if already known. valued is set when
+// fairness comes with the trade; otherwise it is estimated from the lines.
+type fraudContext struct {
+	stage          string
+	actorID        uuid.UUID
+	counterpartyID *uuid.UUID
+	data           db.TradeData
+	fairness       *float64
+	valued         bool
+	repo           *db.RepositoryTradeReview
+}
+
+func (c *fraudContext) reviews() (*db.RepositoryTradeReview, error) {
+	if c.repo == nil {
+		c.repo = db.NewRepositoryTradeReview(logging.GetLogger())
+		if c.repo == nil {
+			return nil, fmt.Errorf("failed to create repository")
+		}
+	}
+	return c.repo, nil
+}
+
+// tradeFairness returns the ratio of the cheaper side of the trade to the
+// dearer one, or nil if an item has no reference price.
+func (c *fraudContext) tradeFairness() (*float64, error) {
+	if c.valued {
+		return c.fairness, nil
+	}
+	c.valued = true
+
+	repo := db.NewRepositoryItemPrice(logging.GetLogger())
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	lines := append(append([]db.TradeItem{}, c.data.OfferedItems...), c.data.RequestedItems...)
+	ids := make([]uuid.UUID, 0, len(lines))
+	for _, line := range lines {
+		ids = append(ids, line.ItemID)
+	}
+	prices, err := repo.FindReferencePrices(context.TODO(), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	var offered, requested float64
+	for _, line := range lines {
+		price, ok := prices[line.ItemID]
+		if !ok {
+			return nil, nil
+		}
+		if line.ItemStatus == "offered" {
+			offered += price * float64(line.Quantity)
+		} else {
+			requested += price * float64(line.Quantity)
+		}
+	}
+
+	if dearer := max(offered, requested); dearer > 0 {
+		fairness := min(offered, requested) / dearer
+		c.fairness = &fairness
+	}
+	return c.fairness, nil
+}
+
+var (
+	fraudChecksOnce sync.Once
+	fraudChecks     []fraudCheck
+)
+
+// loadFraudChecks builds the checks declared in config.yaml once. The checks
+// are validated when the configuration is loaded.
+func loadFraudChecks() []fraudCheck {
+	fraudChecksOnce.Do(func() {
+		for _, cfg := range config.GetConfig().Fraud.Checks {
+			fraudChecks = append(fraudChecks, newFraudCheck(cfg))
+		}
+	})
+	return fraudChecks
+}
+
+func newFraudCheck(cfg config.FraudCheckConfig) fraudCheck {
+	switch cfg.Check {
+	case FraudCheckLopsidedValue:
+		return lopsidedValue(cfg.Weight, cfg.MinFairness)
+	case FraudCheckNewAccountBurst:
+		return newAccountBurst(cfg.Weight, cfg.MaxAge, cfg.Window, cfg.Limit)
+	case FraudCheckPingPong:
+		return pingPong(cfg.Weight, cfg.Window, cfg.Limit)
+	case FraudCheckSharedUserAgent:
+		return sharedUserAgent(cfg.Weight, cfg.Limit)
+	default:
+		panic(fmt.Sprintf("unknown fraud check %q", cfg.Check))
+	}
+}
+
+// lopsidedValue flags trades whose sides differ in value so much that one
+// party gives the other almost everything.
+func lopsidedValue(weight int, minFairness float64) fraudCheck {
+	return func(c *fraudContext) (*db.FraudSignal, error) {
+		fairness, err := c.tradeFairness()
+		if err != nil || fairness == nil || *fairness >= minFairness {
+			return nil, err
+		}
+		return &db.FraudSignal{
+			Check:  FraudCheckLopsidedValue,
+			Score:  weight,
+			Detail: fmt.Sprintf("fairness %.2f is below %.2f", *fairness, minFairness),
+		}, nil
+	}
+}
+
+// newAccountBurst flags accounts younger than maxAge making more than limit
+// trades within window, the trade being scored included.
+func newAccountBurst(weight int, maxAge, window time.Duration, limit int) fraudCheck {
+	return func(c *fraudContext) (*db.FraudSignal, error) {
+		users := db.NewRepositoryUser(logging.GetLogger())
+		if users == nil {
+			return nil, fmt.Errorf("failed to create repository")
+		}
+		joined, err := users.FindCreatedAt(context.TODO(), c.actorID.String())
+		if err != nil {
+			return nil, err
+		}
+		age := time.Since(joined)
+		if age >= maxAge {
+			return nil, nil
+		}
+
+		repo, err := c.reviews()
+		if err != nil {
+			return nil, err
+		}
+		n, err := repo.CountRecentTrades(context.TODO(), c.actorID, time.Now().Add(-window))
+		if err != nil {
+			return nil, err
+		}
+		if n+1 <= limit {
+			return nil, nil
+		}
+		return &db.FraudSignal{
+			Check:  FraudCheckNewAccountBurst,
+			Score:  weight,
+			Detail: fmt.Sprintf("account %s created %s ago made %d trades within %s", c.actorID, age.Truncate(time.Minute), n+1, window),
+		}, nil
+	}
+}
+
+// pingPong flags parties that already traded with each other at least limit
+// times within window.
+func pingPong(weight int, window time.Duration, limit int) fraudCheck {
+	return func(c *fraudContext) (*db.FraudSignal, error) {
+		if c.counterpartyID == nil {
+			return nil, nil
+		}
+
+		repo, err := c.reviews()
+		if err != nil {
+			return nil, err
+		}
+		n, err := repo.CountTradesBetween(context.TODO(), c.actorID, *c.counterpartyID, time.Now().Add(-window))
+		if err != nil {
+			return nil, err
+		}
+		if n < limit {
+			return nil, nil
+		}
+		return &db.FraudSignal{
+			Check:  FraudCheckPingPong,
+			Score:  weight,
+			Detail: fmt.Sprintf("%s and %s traded %d times within %s", c.actorID, *c.counterpartyID, n, window),
+		}, nil
+	}
+}
+
+// sharedUserAgent flags parties logged in with a user agent that at least
+// limit accounts share.
+func sharedUserAgent(weight int, limit int) fraudCheck {
+	return func(c *fraudContext) (*db.FraudSignal, error) {
+		repo, err := c.reviews()
+		if err != nil {
+			return nil, err
+		}
+
+		parties := []uuid.UUID{c.actorID}
+		if c.counterpartyID != nil {
+			parties = append(parties, *c.counterpartyID)
+		}
+		for _, userID := range parties {
+			n, err := repo.CountSharedUserAgent(context.TODO(), userID)
+			if err != nil {
+				return nil, err
+			}
+			if n >= limit {
+				return &db.FraudSignal{
+					Check:  FraudCheckSharedUserAgent,
+					Score:  weight,
+					Detail: fmt.Sprintf("%d accounts share a user agent with %s", n, userID),
+				}, nil
+			}
+		}
+		return nil, nil
+	}
+}
+
+// assessTrade runs every configured fraud check and returns the review to
+// hold the trade with if the signals add up to the hold score, nil
+// otherwise.
+func assessTrade(c *fraudContext) (*db.TradeReviewData, error) {
+	review, err := scoreTrade(c, loadFraudChecks(), config.GetConfig().Fraud.HoldScore)
+	if err != nil || review == nil {
+		return nil, err
+	}
+
+	logging.GetLogger().Infof("Flagged trade of user %s at %s with score %d", c.actorID, c.stage, review.Score)
+	return review, nil
+}
+
+// scoreTrade adds up the signals of checks, see assessTrade.
+func scoreTrade(c *fraudContext, checks []fraudCheck, holdScore int) (*db.TradeReviewData, error) {
+	review := &db.TradeReviewData{Stage: c.stage, Signals: []db.FraudSignal{}}
+	for _, check := range checks {
+		signal, err := check(c)
+		if err != nil {
+			return nil, err
+		}
+		if signal != nil {
+			review.Score += signal.Score
+			review.Signals = append(review.Signals, *signal)
+		}
+	}
+
+	if len(review.Signals) == 0 || review.Score < holdScore {
+		return nil, nil
+	}
+	return review, nil
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+)
+
+func TestLopsidedValue(t *testing.T) {
+	fairness := func(v float64) *float64 { return &v }
+	check := lopsidedValue(50, 0.2)
+
+	tests := []struct {
+		name     string
+		fairness *float64
+		flagged  bool
+	}{
+		{"even swap", fairness(1), false},
+		{"at the threshold", fairness(0.2), false},
+		{"lopsided", fairness(0.05), true},
+		{"no reference price", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signal, err := check(&fraudContext{fairness: tt.fairness, valued: true})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (signal != nil) != tt.flagged {
+				t.Fatalf("got signal %+v, want flagged %v", signal, tt.flagged)
+			}
+			if signal != nil && (signal.Check != FraudCheckLopsidedValue || signal.Score != 50) {
+				t.Errorf("got signal %+v", signal)
+			}
+		})
+	}
+}
+
+func TestPingPongWithoutCounterparty(t *testing.T) {
+	signal, err := pingPong(30, time.Hour, 3)(&fraudContext{actorID: uuid.New()})
+	if err != nil || signal != nil {
+		t.Errorf("got signal %+v, err %v, want neither", signal, err)
+	}
+}
+
+func TestScoreTrade(t *testing.T) {
+	flag := func(score int) fraudCheck {
+		return func(c *fraudContext) (*db.FraudSignal, error) {
+			return &db.FraudSignal{Check: "flag", Score: score}, nil
+		}
+	}
+	pass := func(c *fraudContext) (*db.FraudSignal, error) { return nil, nil }
+	broken := func(c *fraudContext) (*db.FraudSignal, error) { return nil, errors.New("connection refused") }
+
+	tests := []struct {
+		name      string
+		checks    []fraudCheck
+		wantScore int // 0 when the trade is not held
+		wantErr   bool
+	}{
+		{"no checks", nil, 0, false},
+		{"no signals", []fraudCheck{pass, pass}, 0, false},
+		{"below the hold score", []fraudCheck{flag(30), pass}, 0, false},
+		{"signals add up", []fraudCheck{flag(30), pass, flag(20)}, 50, false},
+		{"a single strong signal", []fraudCheck{flag(80)}, 80, false},
+		{"failing check", []fraudCheck{flag(80), broken}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			review, err := scoreTrade(&fraudContext{stage: db.TradeReviewStageCreated}, tt.checks, 50)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantScore == 0 {
+				if review != nil {
+					t.Errorf("trade held with %+v", review)
+				}
+				return
+			}
+			if review == nil || review.Score != tt.wantScore || review.Stage != db.TradeReviewStageCreated {
+				t.Errorf("got review %+v, want score %d", review, tt.wantScore)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+// InventoryItem is a single instance of an item owned by a user.
+type InventoryItem struct {
+	InventoryID uuid.UUID  `json:"inventory_id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	ItemID      uuid.UUID  `json:"item_id" validate:"required"`
+	Name        string     `json:"name"`
+	Rarity      string     `json:"rarity"`
+	Quality     string     `json:"quality,omitempty"`
+	TradeID     *uuid.UUID `json:"trade_id,omitempty"`   // pending trade holding the instance in escrow
+	ListingID   *uuid.UUID `json:"listing_id,omitempty"` // active listing holding the instance in escrow
+	AuctionID   *uuid.UUID `json:"auction_id,omitempty"` // active auction holding the instance in escrow
+	AcquiredAt  time.Time  `json:"acquired_at"`
+}
+
+func AddInventoryItem(userID, itemID uuid.UUID) (uuid.UUID, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryInventory(logger)
+
+	if repo == nil {
+		return uuid.Nil, fmt.Errorf("failed to create repository")
+	}
+
+	id, err := repo.Create(context.TODO(), db.InventoryItemData{
+		UserID: userID,
+		ItemID: itemID,
+	})
+	if err != nil {
+		logger.Infof("Failed to add inventory item: %v", err)
+		return uuid.Nil, err
+	}
+	return id.(uuid.UUID), nil
+}
+
+func LoadInventory(userID string) ([]*InventoryItem, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryInventory(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	data, err := repo.FindByUserID(context.TODO(), userID)
+	if err != nil {
+		logger.Infof("Failed to load inventory: %v", err)
+		return []*InventoryItem{}, err
+	}
+
+	items := make([]*InventoryItem, 0, len(data))
+	for _, it := range data {
+		items = append(items, &InventoryItem{
+			InventoryID: it.ID,
+			UserID:      it.UserID,
+			ItemID:      it.ItemID,
+			Name:        it.Name,
+			Rarity:      it.Rarity,
+			Quality:     it.Quality,
+			TradeID:     it.TradeID,
+			ListingID:   it.ListingID,
+			AuctionID:   it.AuctionID,
+			AcquiredAt:  it.AcquiredAt,
+		})
+	}
+	return items, nil
+}
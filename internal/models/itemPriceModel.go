@@ -0,0 +1,78 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+// ReferencePrice is the price used to estimate the value of the item in
+// trades: the most recent median with actual sales, falling back to averages.
+// The second result is false if the item has never been sold.
+func (p ItemPrice) ReferencePrice() (float64, bool) {
+	for _, price := range []float64{
+		p.Days7.Median,
+		p.Days30.Median,
+		p.Hours24.Median,
+		p.AllTime.Median,
+		p.Days7.Average,
+		p.Days30.Average,
+		p.AllTime.Average,
+		p.OPSKins,
+	} {
+		if price > 0 {
+			return price, true
+		}
+	}
+	return 0, false
+}
+
+// SaveItemPrices stores the market prices of every item in the response and
+// returns how many prices were saved.
+func SaveItemPrices(resp *ItemsResponse) (int, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryItemPrice(logger)
+
+	if repo == nil {
+		return 0, fmt.Errorf("failed to create repository")
+	}
+
+	prices := make([]db.ItemPriceData, 0, len(resp.ItemsList))
+	for name, detail := range resp.ItemsList {
+		if detail.Name != "" {
+			name = detail.Name
+		}
+
+		data := db.ItemPriceData{
+			Name:     name,
+			Currency: resp.Currency,
+			Hours24:  detail.Price.Hours24.stats(),
+			Days7:    detail.Price.Days7.stats(),
+			Days30:   detail.Price.Days30.stats(),
+			AllTime:  detail.Price.AllTime.stats(),
+			OPSKins:  detail.Price.OPSKins,
+		}
+		if price, ok := detail.Price.ReferencePrice(); ok {
+			data.ReferencePrice = &price
+		}
+
+		prices = append(prices, data)
+	}
+
+	if err := repo.UpsertMany(context.TODO(), prices); err != nil {
+		logger.Infof("Failed to save item prices: %v", err)
+		return 0, err
+	}
+	return len(prices), nil
+}
+
+func (d PriceDetail) stats() db.PriceStats {
+	return db.PriceStats{
+		Average: d.Average,
+		Median:  d.Median,
+		Lowest:  d.LowestPrice,
+		Highest: d.HighestPrice,
+	}
+}
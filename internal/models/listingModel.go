@@ -0,0 +1,195 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/config"
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+const (
+	ListingStatusActive    = "active"
+	ListingStatusSold      = "sold"
+	ListingStatusWithdrawn = "withdrawn"
+)
+
+var (
+	ErrListingNotFound     = db.ErrListingNotFound
+	ErrListingNotActive    = db.ErrListingNotActive
+	ErrInvalidPrice        = db.ErrInvalidPrice
+	ErrListingForbidden    = errors.New("operation is not allowed for this user")
+	ErrInvalidListingQuery = errors.New("invalid listing query")
+)
+
+// Listing is an item put up for sale at a fixed price. The listed inventory
+// instance stays in escrow until the listing is sold or withdrawn.
+type Listing struct {
+	ListingID uuid.UUID  `json:"listing_id"`
+	SellerID  uuid.UUID  `json:"seller_id"`
+	ItemID    uuid.UUID  `json:"item_id" validate:"required"`
+	Name      string     `json:"name"`
+	Rarity    string     `json:"rarity"`
+	Quality   string     `json:"quality,omitempty"`
+	Price     float64    `json:"price" validate:"required,gte=0.01"`
+	Currency  string     `json:"currency" validate:"omitempty,min=3,max=10"`
+	Status    string     `json:"status"`
+	BuyerID   *uuid.UUID `json:"buyer_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+}
+
+// ListingQuery filters a listing of listings. Empty fields match everything.
+type ListingQuery struct {
+	Status   string
+	SellerID uuid.UUID
+	ItemID   uuid.UUID
+}
+
+func (l *Listing) Save() (uuid.UUID, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryListing(logger)
+
+	if repo == nil {
+		return uuid.Nil, fmt.Errorf("failed to create repository")
+	}
+
+	id, err := repo.Create(context.TODO(), db.ListingData{
+		SellerID: l.SellerID,
+		ItemID:   l.ItemID,
+		Price:    l.Price,
+		Currency: l.Currency,
+	}, config.GetConfig().Market.DefaultCurrency)
+	if err != nil {
+		logger.Infof("Failed to create listing: %v", err)
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+func LoadListings(query ListingQuery) ([]*Listing, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryListing(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	switch query.Status {
+	case "", ListingStatusActive, ListingStatusSold, ListingStatusWithdrawn:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidListingQuery, query.Status)
+	}
+
+	data, err := repo.FindAll(context.TODO(), db.ListingFilter{
+		Status:   query.Status,
+		SellerID: query.SellerID,
+		ItemID:   query.ItemID,
+	})
+	if err != nil {
+		logger.Infof("Failed to load listings: %v", err)
+		return []*Listing{}, err
+	}
+
+	listings := make([]*Listing, 0, len(data))
+	for _, l := range data {
+		listings = append(listings, newListingFromData(l))
+	}
+	return listings, nil
+}
+
+func LoadListingByID(listingID string) (*Listing, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryListing(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	data, err := repo.FindOne(context.TODO(), listingID)
+	if err != nil {
+		logger.Infof("Failed to load listing by ID: %v", err)
+		return nil, err
+	}
+	return newListingFromData(data), nil
+}
+
+// BuyListing sells the active listing to buyer and moves the item into the
+// buyer's inventory.
+func BuyListing(listingID string, buyer *Token) (*Listing, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryListing(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	listing, err := LoadListingByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if listing.SellerID == buyer.UserID {
+		return nil, fmt.Errorf("%w: seller cannot buy own listing", ErrListingForbidden)
+	}
+	if listing.Status != ListingStatusActive {
+		return nil, fmt.Errorf("%w: %s", ErrListingNotActive, listing.Status)
+	}
+
+	if err := repo.Buy(context.TODO(), listing.ListingID, buyer.UserID); err != nil {
+		logger.Infof("Failed to buy listing: %v", err)
+		return nil, err
+	}
+
+	return LoadListingByID(listingID)
+}
+
+// WithdrawListing takes the active listing off the market. Only the seller
+// and admins may withdraw a listing.
+func WithdrawListing(listingID string, actor *Token) (*Listing, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryListing(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	listing, err := LoadListingByID(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if actor.UserRole != "admin" && listing.SellerID != actor.UserID {
+		return nil, fmt.Errorf("%w: only the seller can withdraw a listing", ErrListingForbidden)
+	}
+	if listing.Status != ListingStatusActive {
+		return nil, fmt.Errorf("%w: %s", ErrListingNotActive, listing.Status)
+	}
+
+	if err := repo.Withdraw(context.TODO(), listing.ListingID); err != nil {
+		logger.Infof("Failed to withdraw listing: %v", err)
+		return nil, err
+	}
+
+	return LoadListingByID(listingID)
+}
+
+func newListingFromData(data db.ListingData) *Listing {
+	return &Listing{
+		ListingID: data.ID,
+		SellerID:  data.SellerID,
+		ItemID:    data.ItemID,
+		Name:      data.Name,
+		Rarity:    data.Rarity,
+		Quality:   data.Quality,
+		Price:     data.Price,
+		Currency:  data.Currency,
+		Status:    data.Status,
+		BuyerID:   data.BuyerID,
+		CreatedAt: data.CreatedAt,
+		ClosedAt:  data.ClosedAt,
+	}
+}
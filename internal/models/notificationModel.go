@@ -0,0 +1,89 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+const (
+	NotificationWishlistMatch = db.NotificationWishlistMatch
+
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 200
+)
+
+var (
+	ErrNotificationNotFound     = db.ErrNotificationNotFound
+	ErrInvalidNotificationQuery = errors.New("invalid notification query")
+)
+
+// Notification tells a user about something that happened on the platform,
+// e.g. a new trade offering an item from their wishlist.
+type Notification struct {
+	NotificationID uuid.UUID  `json:"notification_id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	Kind           string     `json:"kind"`
+	TradeID        *uuid.UUID `json:"trade_id,omitempty"`
+	ItemID         *uuid.UUID `json:"item_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ReadAt         *time.Time `json:"read_at,omitempty"`
+}
+
+// LoadNotifications returns the latest notifications of the user, newest first.
+func LoadNotifications(userID string, unreadOnly bool, limit int) ([]*Notification, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryNotification(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	switch {
+	case limit == 0:
+		limit = defaultNotificationLimit
+	case limit < 0 || limit > maxNotificationLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidNotificationQuery, maxNotificationLimit)
+	}
+
+	data, err := repo.FindByUserID(context.TODO(), userID, unreadOnly, limit)
+	if err != nil {
+		logger.Infof("Failed to load notifications: %v", err)
+		return []*Notification{}, err
+	}
+
+	notifications := make([]*Notification, 0, len(data))
+	for _, n := range data {
+		notifications = append(notifications, &Notification{
+			NotificationID: n.ID,
+			UserID:         n.UserID,
+			Kind:           n.Kind,
+			TradeID:        n.TradeID,
+			ItemID:         n.ItemID,
+			CreatedAt:      n.CreatedAt,
+			ReadAt:         n.ReadAt,
+		})
+	}
+	return notifications, nil
+}
+
+func MarkNotificationRead(userID, notificationID string) error {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryNotification(logger)
+
+	if repo == nil {
+		return fmt.Errorf("failed to create repository")
+	}
+
+	if err := repo.MarkRead(context.TODO(), userID, notificationID); err != nil {
+		logger.Infof("Failed to mark notification as read: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/config"
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+const (
+	PriceIntervalHour = db.PriceBucketHour
+	PriceIntervalDay  = db.PriceBucketDay
+	PriceIntervalWeek = db.PriceBucketWeek
+)
+
+// defaultPriceRanges is how far back the history goes when no start is
+// given, per interval.
+var defaultPriceRanges = map[string]time.Duration{
+	PriceIntervalHour: 7 * 24 * time.Hour,
+	PriceIntervalDay:  90 * 24 * time.Hour,
+	PriceIntervalWeek: 2 * 365 * 24 * time.Hour,
+}
+
+var ErrInvalidPriceHistoryQuery = errors.New("invalid price history query")
+
+// PriceHistory is the price of an item over time, one point per interval and
+// currency.
+type PriceHistory struct {
+	ItemID   uuid.UUID           `json:"item_id"`
+	Interval string              `json:"interval"`
+	From     time.Time           `json:"from"`
+	To       time.Time           `json:"to"`
+	Points   []db.PricePointData `json:"points"`
+}
+
+// PriceHistoryQuery selects the points of a price history. Zero values fall
+// back to daily points up to now.
+type PriceHistoryQuery struct {
+	Interval string
+	From     time.Time
+	To       time.Time
+	Currency string
+}
+
+// LoadPriceHistory returns the price history of an item.
+func LoadPriceHistory(itemID uuid.UUID, query PriceHistoryQuery) (*PriceHistory, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryPriceHistory(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	if query.Interval == "" {
+		query.Interval = PriceIntervalDay
+	}
+	span, ok := defaultPriceRanges[query.Interval]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown interval %q", ErrInvalidPriceHistoryQuery, query.Interval)
+	}
+	if query.To.IsZero() {
+		query.To = time.Now()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-span)
+	}
+	if query.From.After(query.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidPriceHistoryQuery)
+	}
+
+	points, err := repo.FindHistory(context.TODO(), itemID, query.Interval, query.From, query.To, query.Currency)
+	if err != nil {
+		logger.Infof("Failed to load price history: %v", err)
+		return nil, err
+	}
+
+	return &PriceHistory{
+		ItemID:   itemID,
+		Interval: query.Interval,
+		From:     query.From,
+		To:       query.To,
+		Points:   points,
+	}, nil
+}
+
+// RollupPriceHistory refreshes the recent buckets of every interval from the
+// import snapshots and completed trades. It is run by the scheduler.
+func RollupPriceHistory() {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryPriceHistory(logger)
+
+	if repo == nil {
+		logger.Fatal("failed to create repository")
+	}
+
+	since := time.Now().Add(-config.GetConfig().PriceHistory.RollupLookback)
+	for _, bucket := range db.PriceBuckets {
+		n, err := repo.Rollup(context.TODO(), bucket, since)
+		if err != nil {
+			logger.Errorf("Error rolling up %s price history: %v", bucket, err)
+			continue
+		}
+		logger.Infof("Rolled up %d %s price buckets", n, bucket)
+	}
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+const (
+	minRatingScore      = 1
+	maxRatingScore      = 5
+	defaultRatingsLimit = 50
+	maxRatingsLimit     = 200
+)
+
+var (
+	ErrTradeNotCompleted  = db.ErrTradeNotCompleted
+	ErrNotTradeParty      = db.ErrNotTradeParty
+	ErrTradeAlreadyRated  = db.ErrTradeAlreadyRated
+	ErrInvalidRatingQuery = errors.New("invalid rating query")
+)
+
+// TradeRating is the score one party of a completed trade gave the other.
+type TradeRating = db.TradeRatingData
+
+// Reputation sums up the ratings a user received and the trades the user
+// completed.
+type Reputation = db.ReputationData
+
+// NewRating is a rating as submitted by a party of a completed trade.
+type NewRating struct {
+	Score   int    `json:"score" validate:"required,min=1,max=5"`
+	Comment string `json:"comment" validate:"max=1000"`
+}
+
+// UserProfile is a user as shown by GET /api/users/:uuid, together with
+// their reputation.
+type UserProfile struct {
+	*User
+	Reputation *Reputation `json:"reputation"`
+}
+
+// RateTrade stores the rating actor gives the other party of the completed
+// trade tradeID.
+func RateTrade(tradeID string, rating NewRating, actor *Token) (*TradeRating, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryRating(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	id, err := uuid.Parse(tradeID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrTradeNotFound, tradeID)
+	}
+
+	var comment *string
+	if text := strings.TrimSpace(rating.Comment); text != "" {
+		comment = &text
+	}
+
+	data, err := repo.Create(context.TODO(), id, actor.UserID, rating.Score, comment)
+	if err != nil {
+		logger.Infof("Failed to rate trade: %v", err)
+		return nil, err
+	}
+	return &data, nil
+}
+
+// LoadUserRatings returns the latest ratings the user received, newest
+// first.
+func LoadUserRatings(userID uuid.UUID, limit int) ([]TradeRating, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryRating(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	switch {
+	case limit == 0:
+		limit = defaultRatingsLimit
+	case limit < 0 || limit > maxRatingsLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidRatingQuery, maxRatingsLimit)
+	}
+
+	ratings, err := repo.FindByRatee(context.TODO(), userID, limit)
+	if err != nil {
+		logger.Infof("Failed to load user ratings: %v", err)
+		return nil, err
+	}
+	return ratings, nil
+}
+
+// LoadUserProfile returns the user together with their reputation.
+func LoadUserProfile(id string) (*UserProfile, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryRating(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	user, err := LoadUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	rep, err := repo.FindReputation(context.TODO(), user.UserId)
+	if err != nil {
+		logger.Infof("Failed to load reputation: %v", err)
+		return nil, err
+	}
+	return &UserProfile{User: user, Reputation: &rep}, nil
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/receipt"
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+var (
+	ErrReceiptNotFound = db.ErrReceiptNotFound
+	ErrNoSigningKey    = receipt.ErrNoSigningKey
+)
+
+// TradeReceipt is the signed proof of a completed trade. Signature is the
+// base64 encoded Ed25519 signature of the bytes of Receipt exactly as served;
+// it can be checked with the key published by LoadReceiptPublicKey.
+type TradeReceipt struct {
+	TradeID   uuid.UUID       `json:"trade_id"`
+	Receipt   json.RawMessage `json:"receipt"`
+	Signature string          `json:"signature"`
+	Algorithm string          `json:"algorithm"`
+	KeyID     string          `json:"key_id"`
+	IssuedAt  time.Time       `json:"issued_at"`
+}
+
+// ReceiptPublicKey is the key verifying trade receipts, base64 encoded.
+type ReceiptPublicKey struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+}
+
+// LoadTradeReceipt returns the receipt of a completed trade. Receipts of
+// private trades are only shown to their parties and admins; to anybody else
+// they do not exist.
+func LoadTradeReceipt(tradeID string, viewer *Token) (*TradeReceipt, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryReceipt(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	data, err := repo.FindOne(context.TODO(), tradeID)
+	if err != nil {
+		logger.Infof("Failed to load trade receipt: %v", err)
+		return nil, err
+	}
+
+	if data.RecipientID != nil && !receiptVisibleTo(data, viewer) {
+		return nil, fmt.Errorf("%w: %s", ErrReceiptNotFound, tradeID)
+	}
+
+	return &TradeReceipt{
+		TradeID:   data.TradeID,
+		Receipt:   json.RawMessage(data.Payload),
+		Signature: data.Signature,
+		Algorithm: receipt.Algorithm,
+		KeyID:     data.KeyID,
+		IssuedAt:  data.CreatedAt,
+	}, nil
+}
+
+func receiptVisibleTo(data db.TradeReceiptData, viewer *Token) bool {
+	if viewer == nil {
+		return false
+	}
+	if viewer.UserRole == "admin" || viewer.UserID == data.OwnerID || viewer.UserID == *data.RecipientID {
+		return true
+	}
+	return data.CounterpartyID != nil && viewer.UserID == *data.CounterpartyID
+}
+
+// LoadReceiptPublicKey returns the public key receipts are currently signed
+// with.
+func LoadReceiptPublicKey() (*ReceiptPublicKey, error) {
+	pub, keyID, err := receipt.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReceiptPublicKey{
+		Algorithm: receipt.Algorithm,
+		KeyID:     keyID,
+		PublicKey: base64.StdEncoding.EncodeToString(pub),
+	}, nil
+}
@@ -0,0 +1,188 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+const (
+	defaultStatsDays     = 30
+	maxStatsDays         = 366
+	defaultTopItemsLimit = 20
+	maxTopItemsLimit     = 200
+)
+
+var ErrInvalidStatsQuery = errors.New("invalid stats query")
+
+// StatsQuery selects the days the statistics are computed over. Both days
+// are included; zero values fall back to the last 30 days.
+type StatsQuery struct {
+	From  time.Time
+	To    time.Time
+	Limit int
+}
+
+// StatsRange is the first and the last day covered by a statistic.
+type StatsRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type TopItemsStats struct {
+	StatsRange
+	Items []db.ItemVolumeData `json:"items"`
+}
+
+type TradeCountStats struct {
+	StatsRange
+	Days []db.TradeStatusCountData `json:"days"`
+}
+
+type AcceptanceStats struct {
+	StatsRange
+	db.AcceptanceStatsData
+}
+
+type TraderStats struct {
+	StatsRange
+	db.TraderStatsData
+}
+
+// normalize fills in the default range and checks its bounds.
+func (q *StatsQuery) normalize() error {
+	if q.To.IsZero() {
+		q.To = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	if q.From.IsZero() {
+		q.From = q.To.AddDate(0, 0, 1-defaultStatsDays)
+	}
+	if q.From.After(q.To) {
+		return fmt.Errorf("%w: from must not be after to", ErrInvalidStatsQuery)
+	}
+	if q.To.Sub(q.From) >= maxStatsDays*24*time.Hour {
+		return fmt.Errorf("%w: range must not exceed %d days", ErrInvalidStatsQuery, maxStatsDays)
+	}
+	return nil
+}
+
+// end is the exclusive upper bound of the range.
+func (q StatsQuery) end() time.Time {
+	return q.To.AddDate(0, 0, 1)
+}
+
+// LoadTopTradedItems returns the items with the most completed trades.
+func LoadTopTradedItems(query StatsQuery) (*TopItemsStats, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryStats(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	if err := query.normalize(); err != nil {
+		return nil, err
+	}
+	switch {
+	case query.Limit == 0:
+		query.Limit = defaultTopItemsLimit
+	case query.Limit < 0 || query.Limit > maxTopItemsLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidStatsQuery, maxTopItemsLimit)
+	}
+
+	items, err := repo.FindTopItems(context.TODO(), query.From, query.end(), query.Limit)
+	if err != nil {
+		logger.Infof("Failed to load top traded items: %v", err)
+		return nil, err
+	}
+
+	return &TopItemsStats{StatsRange: StatsRange{From: query.From, To: query.To}, Items: items}, nil
+}
+
+// LoadTradeCounts returns the number of trades per day and status.
+func LoadTradeCounts(query StatsQuery) (*TradeCountStats, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryStats(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	if err := query.normalize(); err != nil {
+		return nil, err
+	}
+
+	days, err := repo.FindTradeCounts(context.TODO(), query.From, query.end())
+	if err != nil {
+		logger.Infof("Failed to load trade counts: %v", err)
+		return nil, err
+	}
+
+	return &TradeCountStats{StatsRange: StatsRange{From: query.From, To: query.To}, Days: days}, nil
+}
+
+// LoadAcceptanceStats returns how long trades accepted in the range waited
+// for acceptance.
+func LoadAcceptanceStats(query StatsQuery) (*AcceptanceStats, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryStats(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	if err := query.normalize(); err != nil {
+		return nil, err
+	}
+
+	stats, err := repo.FindAcceptanceTime(context.TODO(), query.From, query.end())
+	if err != nil {
+		logger.Infof("Failed to load acceptance stats: %v", err)
+		return nil, err
+	}
+
+	return &AcceptanceStats{StatsRange: StatsRange{From: query.From, To: query.To}, AcceptanceStatsData: *stats}, nil
+}
+
+// LoadTraderStats returns the number of users active in trades.
+func LoadTraderStats(query StatsQuery) (*TraderStats, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryStats(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	if err := query.normalize(); err != nil {
+		return nil, err
+	}
+
+	stats, err := repo.FindTraderCounts(context.TODO(), query.From, query.end())
+	if err != nil {
+		logger.Infof("Failed to load trader stats: %v", err)
+		return nil, err
+	}
+
+	return &TraderStats{StatsRange: StatsRange{From: query.From, To: query.To}, TraderStatsData: *stats}, nil
+}
+
+// RefreshTradeStats recomputes the materialized statistics. It is run by the
+// scheduler.
+func RefreshTradeStats() {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryStats(logger)
+
+	if repo == nil {
+		logger.Fatal("failed to create repository")
+	}
+
+	if err := repo.Refresh(context.TODO()); err != nil {
+		logger.Errorf("Error refreshing trade stats: %v", err)
+		return
+	}
+	logger.Info("Refreshed trade stats")
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+// TradeEvent is an entry of the append-only history of a trade. ActorID is
+// empty for changes made by the system, e.g. expiry.
+type TradeEvent struct {
+	EventID   uuid.UUID       `json:"event_id"`
+	TradeID   uuid.UUID       `json:"trade_id"`
+	Type      string          `json:"type"`
+	ActorID   *uuid.UUID      `json:"actor_id,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// LoadTradeHistory returns the history of the trade, or ErrTradeNotFound if
+// the trade is private and viewer is not one of its parties. The parties are
+// read from the creation event, so the history of a deleted trade stays as
+// private as the trade was.
+func LoadTradeHistory(tradeID string, viewer *Token) ([]*TradeEvent, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryTradeEvent(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	data, err := repo.FindByTradeID(context.TODO(), tradeID)
+	if err != nil {
+		logger.Infof("Failed to load trade history: %v", err)
+		return []*TradeEvent{}, err
+	}
+
+	events := make([]*TradeEvent, 0, len(data))
+	for _, ev := range data {
+		if ev.EventType == db.TradeEventCreated {
+			var parties struct {
+				UserID      uuid.UUID  `json:"user_id"`
+				RecipientID *uuid.UUID `json:"recipient_id"`
+			}
+			if err := json.Unmarshal(ev.Payload, &parties); err != nil {
+				return []*TradeEvent{}, err
+			}
+			if !(&Trade{UserID: parties.UserID, RecipientID: parties.RecipientID}).VisibleTo(viewer) {
+				return []*TradeEvent{}, ErrTradeNotFound
+			}
+		}
+
+		events = append(events, &TradeEvent{
+			EventID:   ev.ID,
+			TradeID:   ev.TradeID,
+			Type:      ev.EventType,
+			ActorID:   ev.ActorID,
+			Payload:   ev.Payload,
+			CreatedAt: ev.CreatedAt,
+		})
+	}
+	return events, nil
+}
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+// maxTradeCycles caps the number of 3-way cycles returned for a single trade.
+const maxTradeCycles = 50
+
+// TradeMatches lists open trades that can be swapped with a given trade.
+// Every cycle is ordered [B, C]: the trade wants B's items, B wants C's items
+// and C wants the trade's items.
+type TradeMatches struct {
+	Direct []*Trade   `json:"direct"`
+	Cycles [][]*Trade `json:"cycles"`
+}
+
+func FindTradeMatches(tradeID string, viewer *Token) (*TradeMatches, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryTrade(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	target, err := repo.FindOne(context.TODO(), tradeID)
+	if err != nil {
+		logger.Infof("Failed to load trade by ID: %v", err)
+		return nil, err
+	}
+	if target.TradeID == uuid.Nil {
+		return nil, ErrTradeNotFound
+	}
+	if !(&Trade{UserID: target.UserID, RecipientID: target.RecipientID}).VisibleTo(viewer) {
+		return nil, ErrTradeNotFound
+	}
+	if target.Status != TradeStatusPending {
+		return nil, fmt.Errorf("%w: %s", ErrTradeNotPending, target.Status)
+	}
+
+	open, err := repo.FindMatchCandidates(context.TODO(), target)
+	if err != nil {
+		logger.Infof("Failed to load match candidates: %v", err)
+		return nil, err
+	}
+
+	direct, cycles := matchTrades(target, open)
+
+	matches := &TradeMatches{
+		Direct: make([]*Trade, 0, len(direct)),
+		Cycles: make([][]*Trade, 0, len(cycles)),
+	}
+	for _, data := range direct {
+		trade, err := newTradeFromData(data)
+		if err != nil {
+			return nil, err
+		}
+		matches.Direct = append(matches.Direct, trade)
+	}
+	matches.Direct = VisibleTrades(matches.Direct, viewer)
+	for _, cycle := range cycles {
+		trades, err := newTradesFromData(cycle)
+		if err != nil {
+			return nil, err
+		}
+		matches.Cycles = append(matches.Cycles, trades)
+	}
+
+	return matches, nil
+}
+
+// matchTrades finds the open trades that swap directly with target and the
+// pairs of open trades that close a 3-way cycle with it.
+func matchTrades(target db.TradeData, open []db.TradeData) (direct []db.TradeData, cycles [][]db.TradeData) {
+	var suppliers []db.TradeData // trades offering everything target requests
+	var consumers []db.TradeData // trades requesting only what target offers
+
+	for _, other := range open {
+		if other.TradeID == target.TradeID || other.UserID == target.UserID {
+			continue
+		}
+
+		supplies := satisfies(other, target)
+		consumes := satisfies(target, other)
+
+		// Private trades only swap directly with the party they are addressed to
+		// and never take part in cycles.
+		if target.RecipientID != nil || other.RecipientID != nil {
+			if supplies && consumes && addressedTo(target, other.UserID) && addressedTo(other, target.UserID) {
+				direct = append(direct, other)
+			}
+			continue
+		}
+
+		if supplies && consumes {
+			direct = append(direct, other)
+		}
+		if supplies {
+			suppliers = append(suppliers, other)
+		}
+		if consumes {
+			consumers = append(consumers, other)
+		}
+	}
+
+	for _, b := range suppliers {
+		for _, c := range consumers {
+			if len(cycles) == maxTradeCycles {
+				return direct, cycles
+			}
+			if b.TradeID == c.TradeID || b.UserID == c.UserID {
+				continue
+			}
+			if satisfies(c, b) {
+				cycles = append(cycles, []db.TradeData{b, c})
+			}
+		}
+	}
+
+	return direct, cycles
+}
+
+// addressedTo reports whether userID may take the trade.
+func addressedTo(t db.TradeData, userID uuid.UUID) bool {
+	return t.RecipientID == nil || *t.RecipientID == userID
+}
+
+// satisfies reports whether the items offered in supplier cover every item
+// requested in consumer.
+func satisfies(supplier, consumer db.TradeData) bool {
+	if supplier.UserID == consumer.UserID {
+		return false
+	}
+
+	offered := make(map[uuid.UUID]int, len(supplier.OfferedItems))
+	for _, item := range supplier.OfferedItems {
+		offered[item.ItemID] += item.Quantity
+	}
+
+	for _, item := range consumer.RequestedItems {
+		if offered[item.ItemID] < item.Quantity {
+			return false
+		}
+		offered[item.ItemID] -= item.Quantity
+	}
+
+	return true
+}
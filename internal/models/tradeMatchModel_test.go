@@ -0,0 +1,138 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+)
+
+// swap builds a pending trade of owner offering offered and requesting
+// requested, one copy each.
+func swap(owner uuid.UUID, offered, requested []uuid.UUID) db.TradeData {
+	data := db.TradeData{TradeID: uuid.New(), UserID: owner, Status: TradeStatusPending}
+	for _, id := range offered {
+		data.OfferedItems = append(data.OfferedItems, db.TradeItem{ItemID: id, ItemStatus: "offered", Quantity: 1})
+	}
+	for _, id := range requested {
+		data.RequestedItems = append(data.RequestedItems, db.TradeItem{ItemID: id, ItemStatus: "requested", Quantity: 1})
+	}
+	return data
+}
+
+func tradeIDs(trades []db.TradeData) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(trades))
+	for _, t := range trades {
+		ids = append(ids, t.TradeID)
+	}
+	return ids
+}
+
+func TestSatisfies(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	hook, blade := uuid.New(), uuid.New()
+
+	stack := func(owner uuid.UUID, quantity int, requested int) db.TradeData {
+		return db.TradeData{
+			UserID:         owner,
+			OfferedItems:   []db.TradeItem{{ItemID: hook, ItemStatus: "offered", Quantity: quantity}},
+			RequestedItems: []db.TradeItem{{ItemID: hook, ItemStatus: "requested", Quantity: requested}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		supplier db.TradeData
+		consumer db.TradeData
+		want     bool
+	}{
+		{"offers the requested item", swap(alice, []uuid.UUID{hook}, nil), swap(bob, nil, []uuid.UUID{hook}), true},
+		{"offers another item", swap(alice, []uuid.UUID{blade}, nil), swap(bob, nil, []uuid.UUID{hook}), false},
+		{"offers only part of the request", swap(alice, []uuid.UUID{hook}, nil), swap(bob, nil, []uuid.UUID{hook, blade}), false},
+		{"same owner", swap(alice, []uuid.UUID{hook}, nil), swap(alice, nil, []uuid.UUID{hook}), false},
+		{"enough copies", stack(alice, 3, 0), stack(bob, 0, 3), true},
+		{"too few copies", stack(alice, 2, 0), stack(bob, 0, 3), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := satisfies(tt.supplier, tt.consumer); got != tt.want {
+				t.Errorf("satisfies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTrades(t *testing.T) {
+	alice, bob, carol := uuid.New(), uuid.New(), uuid.New()
+	hook, blade, staff := uuid.New(), uuid.New(), uuid.New()
+
+	t.Run("direct swap", func(t *testing.T) {
+		target := swap(alice, []uuid.UUID{hook}, []uuid.UUID{blade})
+		other := swap(bob, []uuid.UUID{blade}, []uuid.UUID{hook})
+		unrelated := swap(carol, []uuid.UUID{staff}, []uuid.UUID{hook})
+
+		direct, cycles := matchTrades(target, []db.TradeData{other, unrelated})
+		if len(direct) != 1 || direct[0].TradeID != other.TradeID {
+			t.Errorf("direct = %v, want [%s]", tradeIDs(direct), other.TradeID)
+		}
+		if len(cycles) != 0 {
+			t.Errorf("expected no cycles, got %d", len(cycles))
+		}
+	})
+
+	t.Run("3-way cycle", func(t *testing.T) {
+		// alice wants bob's blade, bob wants carol's staff, carol wants alice's hook.
+		target := swap(alice, []uuid.UUID{hook}, []uuid.UUID{blade})
+		b := swap(bob, []uuid.UUID{blade}, []uuid.UUID{staff})
+		c := swap(carol, []uuid.UUID{staff}, []uuid.UUID{hook})
+
+		direct, cycles := matchTrades(target, []db.TradeData{c, b})
+		if len(direct) != 0 {
+			t.Errorf("expected no direct matches, got %v", tradeIDs(direct))
+		}
+		if len(cycles) != 1 || cycles[0][0].TradeID != b.TradeID || cycles[0][1].TradeID != c.TradeID {
+			t.Fatalf("cycles = %v, want [[%s %s]]", cycles, b.TradeID, c.TradeID)
+		}
+	})
+
+	t.Run("own trades are skipped", func(t *testing.T) {
+		target := swap(alice, []uuid.UUID{hook}, []uuid.UUID{blade})
+		own := swap(alice, []uuid.UUID{blade}, []uuid.UUID{hook})
+
+		direct, cycles := matchTrades(target, []db.TradeData{target, own})
+		if len(direct) != 0 || len(cycles) != 0 {
+			t.Errorf("expected no matches, got %v and %d cycles", tradeIDs(direct), len(cycles))
+		}
+	})
+
+	t.Run("private trades match only their recipient", func(t *testing.T) {
+		target := swap(alice, []uuid.UUID{hook}, []uuid.UUID{blade})
+		toAlice := swap(bob, []uuid.UUID{blade}, []uuid.UUID{hook})
+		toAlice.RecipientID = &alice
+		toCarol := swap(bob, []uuid.UUID{blade}, []uuid.UUID{hook})
+		toCarol.RecipientID = &carol
+
+		direct, _ := matchTrades(target, []db.TradeData{toAlice, toCarol})
+		if len(direct) != 1 || direct[0].TradeID != toAlice.TradeID {
+			t.Errorf("direct = %v, want [%s]", tradeIDs(direct), toAlice.TradeID)
+		}
+	})
+
+	t.Run("cycles are capped", func(t *testing.T) {
+		target := swap(alice, []uuid.UUID{hook}, []uuid.UUID{blade})
+		var open []db.TradeData
+		for i := 0; i < maxTradeCycles; i++ {
+			open = append(open,
+				swap(uuid.New(), []uuid.UUID{blade}, []uuid.UUID{staff}),
+				swap(uuid.New(), []uuid.UUID{staff}, []uuid.UUID{hook}),
+			)
+		}
+
+		_, cycles := matchTrades(target, open)
+		if len(cycles) != maxTradeCycles {
+			t.Errorf("got %d cycles, want %d", len(cycles), maxTradeCycles)
+		}
+	})
+}
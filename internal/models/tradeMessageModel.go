@@ -0,0 +1,182 @@
+package model
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+const (
+	defaultMessagePageSize = 50
+	maxMessagePageSize     = 200
+)
+
+var ErrInvalidMessageQuery = errors.New("invalid message query")
+
+// TradeMessage is a message posted to the thread of a trade by one of its
+// participants.
+type TradeMessage struct {
+	MessageID uuid.UUID `json:"message_id"`
+	TradeID   uuid.UUID `json:"trade_id"`
+	SenderID  uuid.UUID `json:"sender_id"`
+	Body      string    `json:"body" validate:"required,max=2000"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TradeMessagePage is one page of a message thread, oldest message first.
+// NextCursor is empty on the last page.
+type TradeMessagePage struct {
+	Messages   []*TradeMessage `json:"messages"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// IsParticipant reports whether the user is a party of the trade: its owner,
+// its recipient or the user who accepted it.
+func (t *Trade) IsParticipant(userID uuid.UUID) bool {
+	return userID == t.UserID ||
+		(t.RecipientID != nil && userID == *t.RecipientID) ||
+		(t.AcceptedBy != nil && userID == *t.AcceptedBy)
+}
+
+// checkMessageAccess loads the trade and verifies that actor may read, or
+// write unless read is set, its messages. Messages outlive deleted trades;
+// admins can still read them.
+func checkMessageAccess(tradeID string, actor *Token, read bool) error {
+	trade, err := LoadTradeByID(tradeID)
+	if err != nil {
+		return err
+	}
+	if trade.TradeID == uuid.Nil && read && actor.UserRole == "admin" {
+		return nil
+	}
+	if trade.TradeID == uuid.Nil || !trade.VisibleTo(actor) {
+		return ErrTradeNotFound
+	}
+	if actor.UserRole != "admin" && !trade.IsParticipant(actor.UserID) {
+		return fmt.Errorf("%w: only trade participants can access its messages", ErrTradeForbidden)
+	}
+	return nil
+}
+
+func PostTradeMessage(tradeID string, sender *Token, m *TradeMessage) (*TradeMessage, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryTradeMessage(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	if err := checkMessageAccess(tradeID, sender, false); err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.Parse(tradeID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := repo.Create(context.TODO(), db.TradeMessageData{
+		TradeID:  id,
+		SenderID: sender.UserID,
+		Body:     m.Body,
+	})
+	if err != nil {
+		logger.Infof("Failed to post trade message: %v", err)
+		return nil, err
+	}
+
+	return newTradeMessageFromData(data), nil
+}
+
+func LoadTradeMessages(tradeID string, viewer *Token, cursor string, limit int) (*TradeMessagePage, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryTradeMessage(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	switch {
+	case limit == 0:
+		limit = defaultMessagePageSize
+	case limit < 0 || limit > maxMessagePageSize:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidMessageQuery, maxMessagePageSize)
+	}
+
+	var after *db.TradeMessageCursor
+	if cursor != "" {
+		c, err := decodeMessageCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = &c
+	}
+
+	if err := checkMessageAccess(tradeID, viewer, true); err != nil {
+		return nil, err
+	}
+
+	data, err := repo.FindPage(context.TODO(), tradeID, after, limit+1)
+	if err != nil {
+		logger.Infof("Failed to load trade messages: %v", err)
+		return nil, err
+	}
+
+	page := &TradeMessagePage{}
+	if len(data) > limit {
+		data = data[:limit]
+		last := data[len(data)-1]
+		page.NextCursor = encodeMessageCursor(db.TradeMessageCursor{CreatedAt: last.CreatedAt, MessageID: last.ID})
+	}
+
+	page.Messages = make([]*TradeMessage, 0, len(data))
+	for _, m := range data {
+		page.Messages = append(page.Messages, newTradeMessageFromData(m))
+	}
+	return page, nil
+}
+
+func newTradeMessageFromData(data db.TradeMessageData) *TradeMessage {
+	return &TradeMessage{
+		MessageID: data.ID,
+		TradeID:   data.TradeID,
+		SenderID:  data.SenderID,
+		Body:      data.Body,
+		CreatedAt: data.CreatedAt,
+	}
+}
+
+// encodeMessageCursor serializes a cursor into an opaque URL-safe token.
+func encodeMessageCursor(c db.TradeMessageCursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.MessageID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeMessageCursor(token string) (db.TradeMessageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return db.TradeMessageCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidMessageQuery)
+	}
+
+	date, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return db.TradeMessageCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidMessageQuery)
+	}
+
+	var c db.TradeMessageCursor
+	if c.CreatedAt, err = time.Parse(time.RFC3339Nano, date); err != nil {
+		return db.TradeMessageCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidMessageQuery)
+	}
+	if c.MessageID, err = uuid.Parse(id); err != nil {
+		return db.TradeMessageCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidMessageQuery)
+	}
+	return c, nil
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+)
+
+func TestMessageCursorRoundTrip(t *testing.T) {
+	want := db.TradeMessageCursor{
+		CreatedAt: time.Date(2024, 5, 6, 7, 8, 9, 987654321, time.UTC),
+		MessageID: uuid.New(),
+	}
+
+	got, err := decodeMessageCursor(encodeMessageCursor(want))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.CreatedAt.Equal(want.CreatedAt) || got.MessageID != want.MessageID {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeMessageCursorMalformed(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "%%%"},
+		{"no separator", encode(uuid.NewString())},
+		{"bad date", encode("05/06/2024|" + uuid.NewString())},
+		{"bad message ID", encode("2024-05-06T07:08:09Z|message")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeMessageCursor(tt.token); !errors.Is(err, ErrInvalidMessageQuery) {
+				t.Errorf("expected ErrInvalidMessageQuery, got %v", err)
+			}
+		})
+	}
+}
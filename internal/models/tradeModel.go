@@ -2,22 +2,78 @@ package model
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 
+	"go-server/internal/config"
 	"go-server/internal/repositories/db"
 	"go-server/pkg/logging"
 )
 
+const (
+	TradeStatusPending   = "pending"
+	TradeStatusAccepted  = "accepted"
+	TradeStatusRejected  = "rejected"
+	TradeStatusCancelled = "cancelled"
+	TradeStatusExpired   = "expired"
+	TradeStatusCompleted = "completed"
+)
+
+var (
+	ErrTradeNotFound          = db.ErrTradeNotFound
+	ErrInvalidTradeTransition = errors.New("invalid trade status transition")
+	ErrTradeNotPending        = db.ErrTradeNotPending
+	ErrTradeInProgress        = db.ErrTradeInProgress
+	ErrTradeForbidden         = errors.New("operation is not allowed for this user")
+	ErrItemNotOwned           = db.ErrItemNotOwned
+	ErrItemReserved           = db.ErrItemReserved
+	ErrTradeVersionConflict   = db.ErrTradeVersionConflict
+	ErrInvalidTradeItem       = errors.New("invalid trade item")
+	ErrInvalidTradeRecipient  = errors.New("invalid trade recipient")
+	ErrTradeUnderReview       = db.ErrTradeUnderReview
+)
+
+// tradeTransitions lists the statuses a trade may move to from each status.
+// Statuses missing from the map are terminal.
+var tradeTransitions = map[string][]string{
+	TradeStatusPending: {
+		TradeStatusAccepted,
+		TradeStatusRejected,
+		TradeStatusCancelled,
+		TradeStatusExpired,
+	},
+	TradeStatusAccepted: {
+		TradeStatusCompleted,
+	},
+}
+
 type Trade struct {
-	TradeID        uuid.UUID `json:"trade_id"`
-	UserID         uuid.UUID `json:"user_id" validate:"required"`
-	Status         string    `json:"status"`
-	Date           time.Time `json:"date"`
-	OfferedItems   []*Item   `json:"offered_items" validate:"required"`
-	RequestedItems []*Item   `json:"requested_items" validate:"required"`
+	TradeID        uuid.UUID    `json:"trade_id"`
+	UserID         uuid.UUID    `json:"user_id" validate:"required"` // owner, taken from the token of the creating request
+	ParentID       *uuid.UUID   `json:"parent_id,omitempty"`
+	AcceptedBy     *uuid.UUID   `json:"accepted_by,omitempty"`
+	RecipientID    *uuid.UUID   `json:"recipient_id,omitempty"` // set for private trades
+	Status         string       `json:"status"`
+	Date           time.Time    `json:"date"`
+	ExpiresAt      *time.Time   `json:"expires_at,omitempty"`
+	Version        int          `json:"version"`
+	OfferedValue   *float64     `json:"offered_value,omitempty"`   // estimated from market prices
+	RequestedValue *float64     `json:"requested_value,omitempty"` // estimated from market prices
+	Fairness       *float64     `json:"fairness,omitempty"`        // cheaper side / dearer side, 1 is an even swap
+	UnderReview    bool         `json:"under_review"`              // held for admin review, cannot be accepted or completed
+	ActorID        uuid.UUID    `json:"-"`                         // user performing Save, recorded in the trade history
+	OfferedItems   []*TradeLine `json:"offered_items" validate:"required"`
+	RequestedItems []*TradeLine `json:"requested_items" validate:"required"`
+}
+
+// TradeLine is an item in a trade together with the number of copies traded.
+// A missing quantity means a single copy.
+type TradeLine struct {
+	*Item
+	Quantity int `json:"quantity"`
 }
 
 // TradeItem is structure of item in trade.
@@ -28,10 +84,10 @@ type TradeItem struct {
 	ItemStatus string    `json:"item_status"` // can be "offered" или "requested"
 }
 
-func NewTrade(userID uuid.UUID, offeredItems, requestedItems []*Item) *Trade {
+func NewTrade(userID uuid.UUID, offeredItems, requestedItems []*TradeLine) *Trade {
 	return &Trade{
 		UserID:         userID,
-		Status:         "pending",
+		Status:         TradeStatusPending,
 		OfferedItems:   offeredItems,
 		RequestedItems: requestedItems,
 	}
@@ -46,34 +102,284 @@ func (t *Trade) Save() (interface{}, error) {
 	}
 
 	var data db.TradeData
+	data.TradeID = t.TradeID
 	data.UserID = t.UserID
-	data.Status = t.Status
-	data.Date = t.Date
-	data.OfferedItems = make([]db.TradeItem, len(t.OfferedItems))
-	data.RequestedItems = make([]db.TradeItem, len(t.RequestedItems))
-
-	for i, item := range t.OfferedItems {
-		data.OfferedItems[i] = db.TradeItem{
-			ItemID:     item.ItemId,
-			ItemStatus: "offered",
-		}
+	data.ParentID = t.ParentID
+	data.RecipientID = t.RecipientID
+	data.ActorID = t.ActorID
+	data.Version = t.Version
+
+	// An update keeps the status and the expiry of the stored trade.
+	if t.TradeID == uuid.Nil {
+		data.Status = TradeStatusPending
+		expiresAt := time.Now().Add(config.GetConfig().Trades.TTL)
+		data.ExpiresAt = &expiresAt
 	}
 
-	for i, item := range t.RequestedItems {
-		data.RequestedItems[i] = db.TradeItem{
-			ItemID:     item.ItemId,
-			ItemStatus: "requested",
-		}
+	if t.RecipientID != nil && (*t.RecipientID == uuid.Nil || *t.RecipientID == t.UserID) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTradeRecipient, t.RecipientID)
+	}
+
+	var err error
+	data.OfferedItems, err = mergeTradeLines(t.OfferedItems, "offered")
+	if err != nil {
+		return nil, err
+	}
+	data.RequestedItems, err = mergeTradeLines(t.RequestedItems, "requested")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTradeRules(&data); err != nil {
+		return nil, err
+	}
+
+	data.Review, err = assessTrade(&fraudContext{
+		stage:          db.TradeReviewStageCreated,
+		actorID:        data.UserID,
+		counterpartyID: data.RecipientID,
+		data:           data,
+	})
+	if err != nil {
+		logger.Infof("Failed to assess trade: %v", err)
+		return nil, err
 	}
 
 	if t.TradeID != uuid.Nil {
 		return repo.Update(context.TODO(), data)
 	} else {
-		return repo.Create(context.TODO(), data)
+		id, err := repo.Create(context.TODO(), data)
+		return id, pendingLimitViolation(err)
 	}
 }
 
-func LoadTradeList() ([]*Trade, error) {
+// mergeTradeLines validates the lines of one side of a trade and folds lines
+// repeating the same item into a single line with the summed quantity.
+func mergeTradeLines(lines []*TradeLine, status string) ([]db.TradeItem, error) {
+	items := make([]db.TradeItem, 0, len(lines))
+	index := make(map[uuid.UUID]int, len(lines))
+
+	for _, line := range lines {
+		if line == nil || line.Item == nil || line.ItemId == uuid.Nil {
+			return nil, fmt.Errorf("%w: %s item has no item_id", ErrInvalidTradeItem, status)
+		}
+
+		quantity := line.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		if quantity < 0 {
+			return nil, fmt.Errorf("%w: quantity of item %s must be positive", ErrInvalidTradeItem, line.ItemId)
+		}
+
+		if i, ok := index[line.ItemId]; ok {
+			items[i].Quantity += quantity
+			continue
+		}
+		index[line.ItemId] = len(items)
+		items = append(items, db.TradeItem{
+			ItemID:     line.ItemId,
+			ItemStatus: status,
+			Quantity:   quantity,
+		})
+	}
+
+	return items, nil
+}
+
+// IsPrivate reports whether the trade is addressed to a single recipient.
+func (t *Trade) IsPrivate() bool {
+	return t.RecipientID != nil
+}
+
+// VisibleTo reports whether viewer may see the trade. Public trades are
+// visible to everyone, private ones only to their sender, their recipient
+// and admins. A nil viewer is an anonymous request.
+func (t *Trade) VisibleTo(viewer *Token) bool {
+	if !t.IsPrivate() {
+		return true
+	}
+	if viewer == nil {
+		return false
+	}
+	return viewer.UserRole == "admin" || viewer.UserID == t.UserID || viewer.UserID == *t.RecipientID
+}
+
+// VisibleTrades drops the trades viewer may not see.
+func VisibleTrades(trades []*Trade, viewer *Token) []*Trade {
+	visible := make([]*Trade, 0, len(trades))
+	for _, t := range trades {
+		if t.VisibleTo(viewer) {
+			visible = append(visible, t)
+		}
+	}
+	return visible
+}
+
+// isClosedTradeStatus reports whether a trade in status can no longer change.
+func isClosedTradeStatus(status string) bool {
+	return len(tradeTransitions[status]) == 0
+}
+
+// CanTransitionTrade reports whether a trade in status from may be moved to status to.
+func CanTransitionTrade(from, to string) bool {
+	for _, next := range tradeTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves the trade to the given status if the state machine allows it.
+func (t *Trade) Transition(to string) error {
+	if !CanTransitionTrade(t.Status, to) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTradeTransition, t.Status, to)
+	}
+	t.Status = to
+	return nil
+}
+
+// isOverdue reports whether a pending trade has outlived its TTL but has not
+// been picked up by the expiry job yet.
+func (t *Trade) isOverdue(now time.Time) bool {
+	return t.Status == TradeStatusPending && t.ExpiresAt != nil && now.After(*t.ExpiresAt)
+}
+
+// ExpireStaleTrades moves every overdue pending trade to the "expired" status.
+// Trades are processed in batches so a large backlog does not hold long locks.
+func ExpireStaleTrades() {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryTrade(logger)
+
+	if repo == nil {
+		logger.Fatal("failed to create repository")
+	}
+
+	batchSize := config.GetConfig().Trades.ExpireBatchSize
+	now := time.Now()
+	total := 0
+
+	for {
+		ids, err := repo.ExpirePending(context.TODO(), now, batchSize)
+		if err != nil {
+			logger.Errorf("Error expiring stale trades: %v", err)
+			break
+		}
+		total += len(ids)
+
+		if len(ids) < batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		logger.Infof("Expired %d stale trades", total)
+	}
+}
+
+func AcceptTrade(tradeID string, actor *Token) (*Trade, error) {
+	return changeTradeStatus(tradeID, TradeStatusAccepted, actor)
+}
+
+func RejectTrade(tradeID string, actor *Token) (*Trade, error) {
+	return changeTradeStatus(tradeID, TradeStatusRejected, actor)
+}
+
+func CancelTrade(tradeID string, actor *Token) (*Trade, error) {
+	return changeTradeStatus(tradeID, TradeStatusCancelled, actor)
+}
+
+// CompleteTrade finalizes an accepted trade and swaps the traded items
+// between the inventories of both parties.
+func CompleteTrade(tradeID string, actor *Token) (*Trade, error) {
+	return changeTradeStatus(tradeID, TradeStatusCompleted, actor)
+}
+
+// checkTradeActor verifies that actor is allowed to move the trade to status.
+// The owner cannot accept their own offer, only the owner can cancel it, and
+// only the two parties can complete it. A private trade is rejected by its
+// recipient; a public one only by users negotiating it, that is with a
+// counter-offer in its thread (countered). Admins may do anything except
+// accepting a private trade, which is reserved to its recipient.
+func checkTradeActor(t *Trade, actor *Token, status string, countered bool) error {
+	if t.IsPrivate() && status == TradeStatusAccepted && actor.UserID != *t.RecipientID {
+		return fmt.Errorf("%w: only the recipient can accept a private trade", ErrTradeForbidden)
+	}
+
+	if actor.UserRole == "admin" {
+		return nil
+	}
+
+	if !t.VisibleTo(actor) {
+		return fmt.Errorf("%w: trade is addressed to another user", ErrTradeForbidden)
+	}
+
+	isOwner := actor.UserID == t.UserID
+	isCounterparty := t.AcceptedBy != nil && *t.AcceptedBy == actor.UserID
+
+	switch status {
+	case TradeStatusAccepted:
+		if isOwner {
+			return fmt.Errorf("%w: owner cannot %s own trade", ErrTradeForbidden, status)
+		}
+	case TradeStatusRejected:
+		if isOwner {
+			return fmt.Errorf("%w: owner cannot %s own trade", ErrTradeForbidden, status)
+		}
+		if t.IsPrivate() && actor.UserID != *t.RecipientID {
+			return fmt.Errorf("%w: only the recipient can reject a private trade", ErrTradeForbidden)
+		}
+		if !t.IsPrivate() && !countered {
+			return fmt.Errorf("%w: only users with a counter-offer can reject a public trade", ErrTradeForbidden)
+		}
+	case TradeStatusCancelled:
+		if !isOwner {
+			return fmt.Errorf("%w: only the owner can cancel a trade", ErrTradeForbidden)
+		}
+	case TradeStatusCompleted:
+		if !isOwner && !isCounterparty {
+			return fmt.Errorf("%w: only trade parties can complete a trade", ErrTradeForbidden)
+		}
+	}
+
+	return nil
+}
+
+// checkTradeAcceptRules evaluates the trade rules binding the user about to
+// accept the trade.
+func checkTradeAcceptRules(t *Trade, userID uuid.UUID) error {
+	data := db.TradeData{TradeID: t.TradeID, UserID: t.UserID}
+
+	var err error
+	data.OfferedItems, err = mergeTradeLines(t.OfferedItems, "offered")
+	if err != nil {
+		return err
+	}
+	data.RequestedItems, err = mergeTradeLines(t.RequestedItems, "requested")
+	if err != nil {
+		return err
+	}
+
+	return checkAcceptRules(data, userID)
+}
+
+// hasCounterOffer reports whether the user made a counter-offer in the
+// negotiation thread of the trade.
+func hasCounterOffer(tradeID string, userID uuid.UUID) (bool, error) {
+	thread, err := LoadNegotiation(tradeID)
+	if err != nil {
+		return false, err
+	}
+	for _, t := range thread {
+		if t.ParentID != nil && t.UserID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func changeTradeStatus(tradeID, status string, actor *Token) (*Trade, error) {
 	logger := logging.GetLogger()
 	repo := db.NewRepositoryTrade(logger)
 
@@ -81,36 +387,85 @@ func LoadTradeList() ([]*Trade, error) {
 		return nil, fmt.Errorf("failed to create repository")
 	}
 
-	data, err := repo.FindAll(context.TODO())
+	trade, err := LoadTradeByID(tradeID)
 	if err != nil {
-		logger.Infof("Failed to load trades: %v", err)
-		return []*Trade{}, err
+		return nil, err
+	}
+	if trade.TradeID == uuid.Nil {
+		return nil, ErrTradeNotFound
 	}
 
-	var trades []*Trade
-	for _, tradeData := range data {
-		offeredItems, err := loadItems(tradeData.OfferedItems)
-		if err != nil {
-			logger.Infof("Failed to load offered items for trade: %v", err)
-			return []*Trade{}, err
+	var countered bool
+	if status == TradeStatusRejected && !trade.IsPrivate() && actor.UserRole != "admin" {
+		if countered, err = hasCounterOffer(tradeID, actor.UserID); err != nil {
+			return nil, err
 		}
+	}
 
-		requestedItems, err := loadItems(tradeData.RequestedItems)
-		if err != nil {
-			logger.Infof("Failed to load requested items for trade: %v", err)
-			return []*Trade{}, err
+	if err := checkTradeActor(trade, actor, status, countered); err != nil {
+		return nil, err
+	}
+
+	if trade.isOverdue(time.Now()) && status != TradeStatusExpired {
+		return nil, fmt.Errorf("%w: trade expired at %s", ErrInvalidTradeTransition, trade.ExpiresAt.Format(time.RFC3339))
+	}
+
+	from := trade.Status
+	if err := trade.Transition(status); err != nil {
+		return nil, err
+	}
+
+	if status == TradeStatusAccepted {
+		if err := checkTradeAcceptRules(trade, actor.UserID); err != nil {
+			return nil, err
 		}
+	}
 
-		trades = append(trades, &Trade{
-			tradeData.TradeID,
-			tradeData.UserID,
-			tradeData.Status,
-			tradeData.Date,
-			offeredItems,
-			requestedItems,
+	var review *db.TradeReviewData
+	if status == TradeStatusAccepted && !trade.UnderReview {
+		review, err = assessTrade(&fraudContext{
+			stage:          db.TradeReviewStageAccepted,
+			actorID:        actor.UserID,
+			counterpartyID: &trade.UserID,
+			data:           db.TradeData{TradeID: trade.TradeID, UserID: trade.UserID},
+			fairness:       trade.Fairness,
+			valued:         true,
 		})
+		if err != nil {
+			logger.Infof("Failed to assess trade: %v", err)
+			return nil, err
+		}
 	}
-	return trades, nil
+
+	if err := repo.UpdateStatus(context.TODO(), tradeID, from, status, actor.UserID, review); err != nil {
+		if errors.Is(err, db.ErrTradeStatusChanged) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTradeTransition, err)
+		}
+		if errors.Is(err, ErrTradeUnderReview) {
+			return nil, err
+		}
+		logger.Infof("Failed to change trade status: %v", err)
+		return nil, err
+	}
+
+	return LoadTradeByID(tradeID)
+}
+
+func LoadTradeList() ([]*Trade, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryTrade(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	data, err := repo.FindAll(context.TODO())
+	if err != nil {
+		logger.Infof("Failed to load trades: %v", err)
+		return []*Trade{}, err
+	}
+
+	return newTradesFromData(data)
 }
 
 func LoadTradeByID(tradeID string) (*Trade, error) {
@@ -127,29 +482,49 @@ func LoadTradeByID(tradeID string) (*Trade, error) {
 		return &Trade{}, err
 	}
 
-	offeredItems, err := loadItems(data.OfferedItems)
+	trade, err := newTradeFromData(data)
 	if err != nil {
-		logger.Infof("Failed to load offered items for trade: %v", err)
 		return &Trade{}, err
 	}
+	return trade, nil
+}
 
-	requestedItems, err := loadItems(data.RequestedItems)
+// UpdateTrade replaces the items of the pending trade t.TradeID. Only its
+// owner and admins may change a trade; its owner, parent, recipient and
+// creation date stay as they are.
+func UpdateTrade(t *Trade, actor *Token) error {
+	current, err := LoadTradeByID(t.TradeID.String())
 	if err != nil {
-		logger.Infof("Failed to load requested items for trade: %v", err)
-		return &Trade{}, err
+		return err
+	}
+	if current.TradeID == uuid.Nil {
+		return ErrTradeNotFound
+	}
+	if err := checkTradeOwner(current, actor); err != nil {
+		return err
+	}
+	if current.Status != TradeStatusPending {
+		return fmt.Errorf("%w: %s", ErrTradeNotPending, current.Status)
 	}
 
-	return &Trade{
-		data.TradeID,
-		data.UserID,
-		data.Status,
-		data.Date,
-		offeredItems,
-		requestedItems,
-	}, nil
+	t.UserID = current.UserID
+	t.ParentID = current.ParentID
+	t.RecipientID = current.RecipientID
+	t.ActorID = actor.UserID
+
+	_, err = t.Save()
+	return err
+}
+
+// checkTradeOwner verifies that actor owns the trade or is an admin.
+func checkTradeOwner(t *Trade, actor *Token) error {
+	if actor.UserRole == "admin" || actor.UserID == t.UserID {
+		return nil
+	}
+	return fmt.Errorf("%w: only the owner can change a trade", ErrTradeForbidden)
 }
 
-func DeleteTradeByID(tradeID string) error {
+func DeleteTradeByID(tradeID string, actor *Token) error {
 	logger := logging.GetLogger()
 	repo := db.NewRepositoryTrade(logger)
 
@@ -157,7 +532,18 @@ func DeleteTradeByID(tradeID string) error {
 		return fmt.Errorf("failed to create repository")
 	}
 
-	if err := repo.Delete(context.TODO(), tradeID); err != nil {
+	trade, err := LoadTradeByID(tradeID)
+	if err != nil {
+		return err
+	}
+	if trade.TradeID == uuid.Nil {
+		return ErrTradeNotFound
+	}
+	if err := checkTradeOwner(trade, actor); err != nil {
+		return err
+	}
+
+	if err := repo.Delete(context.TODO(), tradeID, actor.UserID); err != nil {
 		logger.Infof("Failed to delete trade: %v", err)
 		return err
 	}
@@ -178,30 +564,43 @@ func LoadTradesByItemUUID(itemID string) ([]*Trade, error) {
 		return []*Trade{}, err
 	}
 
-	var trades []*Trade
-	for _, trade := range tradeData {
-		offeredItems, err := loadItems(trade.OfferedItems)
-		if err != nil {
-			logger.Infof("Failed to load items for trade: %v", err)
-			return []*Trade{}, err
-		}
+	return newTradesFromData(tradeData)
+}
 
-		requestedItems, err := loadItems(trade.RequestedItems)
-		if err != nil {
-			logger.Infof("Failed to load items for trade: %v", err)
-			return []*Trade{}, err
-		}
+// LoadIncomingTrades returns the private trades addressed to the user.
+func LoadIncomingTrades(userID string) ([]*Trade, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryTrade(logger)
 
-		trades = append(trades, &Trade{
-			trade.TradeID,
-			trade.UserID,
-			trade.Status,
-			trade.Date,
-			offeredItems,
-			requestedItems,
-		})
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
 	}
-	return trades, nil
+
+	tradeData, err := repo.FindIncoming(context.TODO(), userID)
+	if err != nil {
+		logger.Infof("Failed to load incoming trades: %v", err)
+		return []*Trade{}, err
+	}
+
+	return newTradesFromData(tradeData)
+}
+
+// LoadOutgoingTrades returns the private trades the user sent.
+func LoadOutgoingTrades(userID string) ([]*Trade, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryTrade(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	tradeData, err := repo.FindOutgoing(context.TODO(), userID)
+	if err != nil {
+		logger.Infof("Failed to load outgoing trades: %v", err)
+		return []*Trade{}, err
+	}
+
+	return newTradesFromData(tradeData)
 }
 
 func LoadTradesByUserUUID(userID string) ([]*Trade, error) {
@@ -218,44 +617,185 @@ func LoadTradesByUserUUID(userID string) ([]*Trade, error) {
 		return []*Trade{}, err
 	}
 
-	var trades []*Trade
-	for _, trade := range tradeData {
-		offeredItems, err := loadItems(trade.OfferedItems)
-		if err != nil {
-			logger.Infof("Failed to load items for trade: %v", err)
-			return []*Trade{}, err
-		}
+	return newTradesFromData(tradeData)
+}
 
-		requestedItems, err := loadItems(trade.RequestedItems)
-		if err != nil {
-			logger.Infof("Failed to load items for trade: %v", err)
-			return []*Trade{}, err
-		}
+// CreateCounterOffer stores t as a counter-offer to the pending trade parentID.
+// A counter-offer is always private. A counter-offer to a private trade can
+// only come from one of its parties and is addressed to the other one; a
+// counter-offer to a public trade is addressed to its owner, who cannot
+// counter their own public trade. The trade created for an auction winner
+// cannot be countered, its items are fixed by the auction (ErrAuctionTrade).
+func CreateCounterOffer(parentID string, t *Trade) (uuid.UUID, error) {
+	parent, err := LoadTradeByID(parentID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if parent.TradeID == uuid.Nil {
+		return uuid.Nil, ErrTradeNotFound
+	}
+	if parent.Status != TradeStatusPending {
+		return uuid.Nil, fmt.Errorf("%w: %s", ErrTradeNotPending, parent.Status)
+	}
+
+	switch {
+	case !parent.IsPrivate() && t.UserID == parent.UserID:
+		return uuid.Nil, fmt.Errorf("%w: owner cannot counter own trade", ErrTradeForbidden)
+	case !parent.IsPrivate(), t.UserID == *parent.RecipientID:
+		t.RecipientID = &parent.UserID
+	case t.UserID == parent.UserID:
+		t.RecipientID = parent.RecipientID
+	default:
+		return uuid.Nil, fmt.Errorf("%w: trade is addressed to another user", ErrTradeForbidden)
+	}
+
+	t.TradeID = uuid.Nil
+	t.ParentID = &parent.TradeID
+	t.Status = TradeStatusPending
+
+	id, err := t.Save()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return id.(uuid.UUID), nil
+}
+
+// LoadNegotiation returns the whole negotiation thread the trade belongs to,
+// oldest offer first.
+func LoadNegotiation(tradeID string) ([]*Trade, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryTrade(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	data, err := repo.FindThread(context.TODO(), tradeID)
+	if err != nil {
+		logger.Infof("Failed to load negotiation thread: %v", err)
+		return []*Trade{}, err
+	}
+
+	return newTradesFromData(data)
+}
+
+func newTradesFromData(data []db.TradeData) ([]*Trade, error) {
+	logger := logging.GetLogger()
+
+	items, err := loadTradeItems(data)
+	if err != nil {
+		logger.Infof("Failed to load items for trades: %v", err)
+		return []*Trade{}, err
+	}
+
+	trades := make([]*Trade, 0, len(data))
+	for _, tradeData := range data {
+		closed := isClosedTradeStatus(tradeData.Status)
+		offeredItems := resolveItems(tradeData.OfferedItems, items, closed)
+		requestedItems := resolveItems(tradeData.RequestedItems, items, closed)
 
 		trades = append(trades, &Trade{
-			trade.TradeID,
-			trade.UserID,
-			trade.Status,
-			trade.Date,
-			offeredItems,
-			requestedItems,
+			TradeID:        tradeData.TradeID,
+			UserID:         tradeData.UserID,
+			ParentID:       tradeData.ParentID,
+			AcceptedBy:     tradeData.AcceptedBy,
+			RecipientID:    tradeData.RecipientID,
+			Status:         tradeData.Status,
+			Date:           tradeData.Date,
+			ExpiresAt:      tradeData.ExpiresAt,
+			Version:        tradeData.Version,
+			OfferedValue:   tradeData.OfferedValue,
+			RequestedValue: tradeData.RequestedValue,
+			Fairness:       tradeData.Fairness,
+			UnderReview:    tradeData.UnderReview,
+			OfferedItems:   offeredItems,
+			RequestedItems: requestedItems,
 		})
 	}
 	return trades, nil
 }
 
-func loadItems(tradeItems []db.TradeItem) ([]*Item, error) {
+func newTradeFromData(data db.TradeData) (*Trade, error) {
+	trades, err := newTradesFromData([]db.TradeData{data})
+	if err != nil {
+		return nil, err
+	}
+	return trades[0], nil
+}
+
+// loadTradeItems fetches with a single query every live item the given
+// trades are shown with. Lines of closed trades carrying a snapshot are
+// skipped, see resolveItems.
+func loadTradeItems(data []db.TradeData) (map[uuid.UUID]*Item, error) {
 	logger := logging.GetLogger()
-	var items []*Item
+	repo := db.NewRepositoryItem(logger)
 
-	for _, tradeItem := range tradeItems {
-		item, err := LoadItem(tradeItem.ItemID.String())
-		if err != nil {
-			logger.Infof("Failed to load item: %v", err)
-			return []*Item{}, err
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	var ids []uuid.UUID
+	for _, tradeData := range data {
+		closed := isClosedTradeStatus(tradeData.Status)
+		for _, tradeItem := range append(tradeData.OfferedItems, tradeData.RequestedItems...) {
+			if closed && tradeItem.Snapshot != nil {
+				continue
+			}
+			ids = append(ids, tradeItem.ItemID)
 		}
-		items = append(items, item)
 	}
 
+	items := make(map[uuid.UUID]*Item, len(ids))
+	if len(ids) == 0 {
+		return items, nil
+	}
+
+	itemData, err := repo.FindByIDs(context.TODO(), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, itm := range itemData {
+		items[itm.ItemId] = &Item{
+			itm.ItemId,
+			itm.Name,
+			itm.Rarity,
+			itm.Quality,
+		}
+	}
 	return items, nil
 }
+
+// resolveItems turns trade lines into items. Closed trades show the items as
+// they were when the lines were written, open trades show live items. A line
+// whose item was deleted falls back to its snapshot, or to the bare item ID
+// for lines older than snapshots.
+func resolveItems(tradeItems []db.TradeItem, items map[uuid.UUID]*Item, closed bool) []*TradeLine {
+	resolved := make([]*TradeLine, 0, len(tradeItems))
+
+	for _, tradeItem := range tradeItems {
+		item, ok := items[tradeItem.ItemID]
+		if tradeItem.Snapshot != nil && (closed || !ok) {
+			item = newItemFromSnapshot(tradeItem.ItemID, tradeItem.Snapshot)
+		} else if !ok {
+			item = &Item{ItemId: tradeItem.ItemID}
+		}
+		resolved = append(resolved, &TradeLine{Item: item, Quantity: tradeItem.Quantity})
+	}
+
+	return resolved
+}
+
+func newItemFromSnapshot(itemID uuid.UUID, snapshot *db.ItemSnapshot) *Item {
+	item := &Item{ItemId: itemID}
+	if snapshot.Name != nil {
+		item.Name = *snapshot.Name
+	}
+	if snapshot.Rarity != nil {
+		item.Rarity = *snapshot.Rarity
+	}
+	if snapshot.Quality != nil {
+		item.Quality = *snapshot.Quality
+	}
+	return item
+}
@@ -0,0 +1,193 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+)
+
+func TestCheckTradeActor(t *testing.T) {
+	owner := uuid.New()
+	recipient := uuid.New()
+	counterparty := uuid.New()
+	stranger := uuid.New()
+
+	public := &Trade{UserID: owner}
+	private := &Trade{UserID: owner, RecipientID: &recipient}
+	accepted := &Trade{UserID: owner, AcceptedBy: &counterparty, Status: TradeStatusAccepted}
+
+	user := func(id uuid.UUID) *Token { return &Token{UserID: id, UserRole: "user"} }
+	admin := &Token{UserID: uuid.New(), UserRole: "admin"}
+
+	tests := []struct {
+		name      string
+		trade     *Trade
+		actor     *Token
+		status    string
+		countered bool
+		allowed   bool
+	}{
+		{"stranger accepts public trade", public, user(stranger), TradeStatusAccepted, false, true},
+		{"owner accepts own trade", public, user(owner), TradeStatusAccepted, false, false},
+		{"recipient accepts private trade", private, user(recipient), TradeStatusAccepted, false, true},
+		{"stranger accepts private trade", private, user(stranger), TradeStatusAccepted, false, false},
+		{"admin accepts private trade", private, admin, TradeStatusAccepted, false, false},
+		{"admin accepts public trade", public, admin, TradeStatusAccepted, false, true},
+
+		{"recipient rejects private trade", private, user(recipient), TradeStatusRejected, false, true},
+		{"stranger rejects private trade", private, user(stranger), TradeStatusRejected, true, false},
+		{"owner rejects own trade", public, user(owner), TradeStatusRejected, true, false},
+		{"stranger rejects public trade", public, user(stranger), TradeStatusRejected, false, false},
+		{"countering user rejects public trade", public, user(stranger), TradeStatusRejected, true, true},
+		{"admin rejects public trade", public, admin, TradeStatusRejected, false, true},
+
+		{"owner cancels own trade", public, user(owner), TradeStatusCancelled, false, true},
+		{"recipient cancels private trade", private, user(recipient), TradeStatusCancelled, false, false},
+		{"admin cancels trade", private, admin, TradeStatusCancelled, false, true},
+
+		{"owner completes trade", accepted, user(owner), TradeStatusCompleted, false, true},
+		{"counterparty completes trade", accepted, user(counterparty), TradeStatusCompleted, false, true},
+		{"stranger completes trade", accepted, user(stranger), TradeStatusCompleted, false, false},
+		{"admin completes trade", accepted, admin, TradeStatusCompleted, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTradeActor(tt.trade, tt.actor, tt.status, tt.countered)
+			if tt.allowed && err != nil {
+				t.Fatalf("expected allowed, got %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrTradeForbidden) {
+				t.Fatalf("expected ErrTradeForbidden, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCanTransitionTrade(t *testing.T) {
+	statuses := []string{
+		TradeStatusPending,
+		TradeStatusAccepted,
+		TradeStatusRejected,
+		TradeStatusCancelled,
+		TradeStatusExpired,
+		TradeStatusCompleted,
+	}
+	allowed := map[[2]string]bool{
+		{TradeStatusPending, TradeStatusAccepted}:   true,
+		{TradeStatusPending, TradeStatusRejected}:   true,
+		{TradeStatusPending, TradeStatusCancelled}:  true,
+		{TradeStatusPending, TradeStatusExpired}:    true,
+		{TradeStatusAccepted, TradeStatusCompleted}: true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]string{from, to}]
+			if got := CanTransitionTrade(from, to); got != want {
+				t.Errorf("CanTransitionTrade(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+func TestIsClosedTradeStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		closed bool
+	}{
+		{TradeStatusPending, false},
+		{TradeStatusAccepted, false},
+		{TradeStatusRejected, true},
+		{TradeStatusCancelled, true},
+		{TradeStatusExpired, true},
+		{TradeStatusCompleted, true},
+	}
+
+	for _, tt := range tests {
+		if got := isClosedTradeStatus(tt.status); got != tt.closed {
+			t.Errorf("isClosedTradeStatus(%s) = %v, want %v", tt.status, got, tt.closed)
+		}
+	}
+}
+
+func TestResolveItems(t *testing.T) {
+	itemID := uuid.New()
+	name, rarity := "Dragonclaw Hook", "arcana"
+
+	live := map[uuid.UUID]*Item{itemID: {ItemId: itemID, Name: "Renamed Hook", Rarity: "immortal"}}
+	snapshot := &db.ItemSnapshot{Name: &name, Rarity: &rarity}
+
+	tests := []struct {
+		name     string
+		line     db.TradeItem
+		items    map[uuid.UUID]*Item
+		closed   bool
+		wantName string
+	}{
+		{"open trade shows live item", db.TradeItem{ItemID: itemID, Snapshot: snapshot}, live, false, "Renamed Hook"},
+		{"closed trade shows snapshot", db.TradeItem{ItemID: itemID, Snapshot: snapshot}, live, true, name},
+		{"deleted item falls back to snapshot", db.TradeItem{ItemID: itemID, Snapshot: snapshot}, nil, false, name},
+		{"closed trade without snapshot shows live item", db.TradeItem{ItemID: itemID}, live, true, "Renamed Hook"},
+		{"deleted item without snapshot keeps its ID", db.TradeItem{ItemID: itemID}, nil, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.line.Quantity = 2
+			lines := resolveItems([]db.TradeItem{tt.line}, tt.items, tt.closed)
+			if len(lines) != 1 {
+				t.Fatalf("expected 1 line, got %d", len(lines))
+			}
+			if lines[0].ItemId != itemID || lines[0].Name != tt.wantName || lines[0].Quantity != 2 {
+				t.Errorf("got item %s %q x%d, want %s %q x2", lines[0].ItemId, lines[0].Name, lines[0].Quantity, itemID, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestMergeTradeLines(t *testing.T) {
+	hook, blade := uuid.New(), uuid.New()
+	line := func(id uuid.UUID, quantity int) *TradeLine {
+		return &TradeLine{Item: &Item{ItemId: id}, Quantity: quantity}
+	}
+
+	tests := []struct {
+		name    string
+		lines   []*TradeLine
+		want    []db.TradeItem
+		wantErr bool
+	}{
+		{"no lines", nil, []db.TradeItem{}, false},
+		{"missing quantity is one copy", []*TradeLine{line(hook, 0)}, []db.TradeItem{{ItemID: hook, ItemStatus: "offered", Quantity: 1}}, false},
+		{"repeated item is summed", []*TradeLine{line(hook, 2), line(blade, 1), line(hook, 0)}, []db.TradeItem{
+			{ItemID: hook, ItemStatus: "offered", Quantity: 3},
+			{ItemID: blade, ItemStatus: "offered", Quantity: 1},
+		}, false},
+		{"negative quantity", []*TradeLine{line(hook, -1)}, nil, true},
+		{"nil line", []*TradeLine{nil}, nil, true},
+		{"line without item", []*TradeLine{{Quantity: 1}}, nil, true},
+		{"item without ID", []*TradeLine{line(uuid.Nil, 1)}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeTradeLines(tt.lines, "offered")
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTradeItem) {
+					t.Fatalf("expected ErrInvalidTradeItem, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
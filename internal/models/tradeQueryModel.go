@@ -0,0 +1,179 @@
+package model
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+const (
+	defaultTradePageSize = 50
+	maxTradePageSize     = 200
+)
+
+var ErrInvalidTradeQuery = errors.New("invalid trade query")
+
+// TradeQuery describes the filters, ordering and page requested for a trade
+// listing. Sort is either "date" or "-date" (newest first, the default).
+// MinFairness drops trades whose fairness is lower or cannot be estimated.
+// MinReputation drops trades whose creator has a lower average rating or has
+// not been rated yet.
+type TradeQuery struct {
+	Viewer          *Token // nil for anonymous requests, see Trade.VisibleTo
+	Status          string
+	UserID          uuid.UUID
+	OfferedItemID   uuid.UUID
+	RequestedItemID uuid.UUID
+	Rarity          string
+	CreatedFrom     *time.Time
+	CreatedTo       *time.Time
+	MinFairness     *float64
+	MinReputation   *float64
+	Sort            string
+	Cursor          string
+	Limit           int
+}
+
+// TradePage is one page of a trade listing. NextCursor is empty on the last page.
+type TradePage struct {
+	Trades     []*Trade `json:"trades"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+func LoadTradePage(query TradeQuery) (*TradePage, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryTrade(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	filter, err := query.filter()
+	if err != nil {
+		return nil, err
+	}
+
+	limit := filter.Limit
+	filter.Limit++ // one extra row tells whether there is a next page
+
+	data, err := repo.FindPage(context.TODO(), filter)
+	if err != nil {
+		logger.Infof("Failed to load trades: %v", err)
+		return nil, err
+	}
+
+	page := &TradePage{}
+	if len(data) > limit {
+		data = data[:limit]
+		last := data[len(data)-1]
+		page.NextCursor = encodeTradeCursor(db.TradeCursor{Date: last.Date, TradeID: last.TradeID})
+	}
+
+	page.Trades, err = newTradesFromData(data)
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (q TradeQuery) filter() (db.TradeFilter, error) {
+	filter := db.TradeFilter{
+		Status:          q.Status,
+		UserID:          q.UserID,
+		OfferedItemID:   q.OfferedItemID,
+		RequestedItemID: q.RequestedItemID,
+		Rarity:          q.Rarity,
+		CreatedFrom:     q.CreatedFrom,
+		CreatedTo:       q.CreatedTo,
+		MinFairness:     q.MinFairness,
+		MinReputation:   q.MinReputation,
+		Limit:           q.Limit,
+	}
+
+	if q.Status != "" && !isTradeStatus(q.Status) {
+		return db.TradeFilter{}, fmt.Errorf("%w: unknown status %q", ErrInvalidTradeQuery, q.Status)
+	}
+
+	if q.Viewer != nil {
+		filter.ViewerID = q.Viewer.UserID
+		filter.IncludePrivate = q.Viewer.UserRole == "admin"
+	}
+
+	if q.MinFairness != nil && (*q.MinFairness < 0 || *q.MinFairness > 1) {
+		return db.TradeFilter{}, fmt.Errorf("%w: min_fairness must be between 0 and 1", ErrInvalidTradeQuery)
+	}
+
+	if q.MinReputation != nil && (*q.MinReputation < minRatingScore || *q.MinReputation > maxRatingScore) {
+		return db.TradeFilter{}, fmt.Errorf("%w: min_reputation must be between %d and %d", ErrInvalidTradeQuery, minRatingScore, maxRatingScore)
+	}
+
+	switch q.Sort {
+	case "", "-date":
+		filter.Descending = true
+	case "date":
+		filter.Descending = false
+	default:
+		return db.TradeFilter{}, fmt.Errorf("%w: unsupported sort %q", ErrInvalidTradeQuery, q.Sort)
+	}
+
+	switch {
+	case q.Limit == 0:
+		filter.Limit = defaultTradePageSize
+	case q.Limit < 0 || q.Limit > maxTradePageSize:
+		return db.TradeFilter{}, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidTradeQuery, maxTradePageSize)
+	}
+
+	if q.Cursor != "" {
+		cursor, err := decodeTradeCursor(q.Cursor)
+		if err != nil {
+			return db.TradeFilter{}, err
+		}
+		filter.After = &cursor
+	}
+
+	return filter, nil
+}
+
+func isTradeStatus(status string) bool {
+	switch status {
+	case TradeStatusPending, TradeStatusAccepted, TradeStatusRejected,
+		TradeStatusCancelled, TradeStatusExpired, TradeStatusCompleted:
+		return true
+	}
+	return false
+}
+
+// encodeTradeCursor serializes a cursor into an opaque URL-safe token.
+func encodeTradeCursor(c db.TradeCursor) string {
+	raw := c.Date.UTC().Format(time.RFC3339Nano) + "|" + c.TradeID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeTradeCursor(token string) (db.TradeCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return db.TradeCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidTradeQuery)
+	}
+
+	date, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return db.TradeCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidTradeQuery)
+	}
+
+	var c db.TradeCursor
+	if c.Date, err = time.Parse(time.RFC3339Nano, date); err != nil {
+		return db.TradeCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidTradeQuery)
+	}
+	if c.TradeID, err = uuid.Parse(id); err != nil {
+		return db.TradeCursor{}, fmt.Errorf("%w: malformed cursor", ErrInvalidTradeQuery)
+	}
+	return c, nil
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+)
+
+func TestTradeCursorRoundTrip(t *testing.T) {
+	want := db.TradeCursor{
+		Date:    time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.FixedZone("MSK", 3*60*60)),
+		TradeID: uuid.New(),
+	}
+
+	got, err := decodeTradeCursor(encodeTradeCursor(want))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Date.Equal(want.Date) || got.TradeID != want.TradeID {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeTradeCursorMalformed(t *testing.T) {
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"not base64", "***"},
+		{"no separator", encode("2024-01-02T03:04:05Z")},
+		{"bad date", encode("yesterday|" + uuid.NewString())},
+		{"bad trade ID", encode("2024-01-02T03:04:05Z|42")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeTradeCursor(tt.token); !errors.Is(err, ErrInvalidTradeQuery) {
+				t.Errorf("expected ErrInvalidTradeQuery, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTradeQueryFilter(t *testing.T) {
+	ptr := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name      string
+		query     TradeQuery
+		wantErr   bool
+		wantLimit int
+		wantDesc  bool
+	}{
+		{"defaults", TradeQuery{}, false, defaultTradePageSize, true},
+		{"oldest first", TradeQuery{Sort: "date", Limit: 10}, false, 10, false},
+		{"unknown status", TradeQuery{Status: "lost"}, true, 0, false},
+		{"unknown sort", TradeQuery{Sort: "price"}, true, 0, false},
+		{"limit too large", TradeQuery{Limit: maxTradePageSize + 1}, true, 0, false},
+		{"negative limit", TradeQuery{Limit: -1}, true, 0, false},
+		{"fairness above 1", TradeQuery{MinFairness: ptr(1.5)}, true, 0, false},
+		{"reputation below range", TradeQuery{MinReputation: ptr(0)}, true, 0, false},
+		{"malformed cursor", TradeQuery{Cursor: "***"}, true, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := tt.query.filter()
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTradeQuery) {
+					t.Fatalf("expected ErrInvalidTradeQuery, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if filter.Limit != tt.wantLimit || filter.Descending != tt.wantDesc {
+				t.Errorf("got limit %d descending %v, want %d %v", filter.Limit, filter.Descending, tt.wantLimit, tt.wantDesc)
+			}
+		})
+	}
+}
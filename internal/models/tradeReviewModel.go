@@ -0,0 +1,110 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+const (
+	defaultReviewsLimit = 50
+	maxReviewsLimit     = 200
+	maxReviewNoteLength = 1000
+)
+
+var (
+	ErrTradeReviewNotFound = db.ErrTradeReviewNotFound
+	ErrTradeReviewResolved = db.ErrTradeReviewResolved
+	ErrInvalidReviewQuery  = errors.New("invalid review query")
+)
+
+// TradeReview is a trade held for review together with the fraud signals
+// that flagged it.
+type TradeReview = db.TradeReviewData
+
+// ReviewResolution is the optional note an admin leaves when resolving a
+// review.
+type ReviewResolution struct {
+	Note string `json:"note"`
+}
+
+// LoadTradeReviews returns the review queue: up to limit reviews in the
+// given status, open ones by default, highest score first.
+func LoadTradeReviews(status string, limit int) ([]TradeReview, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryTradeReview(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	switch status {
+	case "":
+		status = db.TradeReviewOpen
+	case db.TradeReviewOpen, db.TradeReviewApproved, db.TradeReviewRejected:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidReviewQuery, status)
+	}
+	switch {
+	case limit == 0:
+		limit = defaultReviewsLimit
+	case limit < 0 || limit > maxReviewsLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidReviewQuery, maxReviewsLimit)
+	}
+
+	reviews, err := repo.FindByStatus(context.TODO(), status, limit)
+	if err != nil {
+		logger.Infof("Failed to load trade reviews: %v", err)
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// ApproveTradeReview releases the hold on the reviewed trade.
+func ApproveTradeReview(reviewID string, res ReviewResolution, actor *Token) (*TradeReview, error) {
+	return resolveTradeReview(reviewID, db.TradeReviewApproved, res, actor)
+}
+
+// RejectTradeReview confirms the suspicion and rejects the reviewed trade.
+func RejectTradeReview(reviewID string, res ReviewResolution, actor *Token) (*TradeReview, error) {
+	return resolveTradeReview(reviewID, db.TradeReviewRejected, res, actor)
+}
+
+func resolveTradeReview(reviewID, status string, res ReviewResolution, actor *Token) (*TradeReview, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryTradeReview(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	id, err := uuid.Parse(reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrTradeReviewNotFound, reviewID)
+	}
+
+	var note *string
+	if text := strings.TrimSpace(res.Note); text != "" {
+		if len([]rune(text)) > maxReviewNoteLength {
+			return nil, fmt.Errorf("%w: note must not exceed %d characters", ErrInvalidReviewQuery, maxReviewNoteLength)
+		}
+		note = &text
+	}
+
+	if err := repo.Resolve(context.TODO(), id, status, actor.UserID, note); err != nil {
+		logger.Infof("Failed to resolve trade review: %v", err)
+		return nil, err
+	}
+
+	review, err := repo.FindOne(context.TODO(), reviewID)
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
@@ -0,0 +1,306 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/config"
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+// Trade rule names as declared in the trade_rules section of config.yaml.
+const (
+	TradeRuleMaxItemsPerSide   = config.TradeRuleMaxItemsPerSide
+	TradeRuleNoItemOnBothSides = config.TradeRuleNoItemOnBothSides
+	TradeRuleMinAccountAge     = config.TradeRuleMinAccountAge
+	TradeRuleMaxPendingTrades  = config.TradeRuleMaxPendingTrades
+)
+
+var ErrTradeRuleViolation = errors.New("trade violates trade rules")
+
+// RuleViolation tells the client which rule a trade broke and why.
+type RuleViolation struct {
+	Rule    string     `json:"rule"`
+	Message string     `json:"message"`
+	ItemID  *uuid.UUID `json:"item_id,omitempty"`
+}
+
+// TradeRuleError carries every violation found in a trade. It matches
+// ErrTradeRuleViolation with errors.Is.
+type TradeRuleError struct {
+	Violations []RuleViolation
+}
+
+func (e *TradeRuleError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return fmt.Sprintf("%v: %s", ErrTradeRuleViolation, strings.Join(messages, "; "))
+}
+
+func (e *TradeRuleError) Unwrap() error {
+	return ErrTradeRuleViolation
+}
+
+// tradeRule checks one policy against a trade about to be stored, or about
+// to be accepted when c.accepting is set.
+type tradeRule func(c *tradeRuleContext) ([]RuleViolation, error)
+
+// tradeRuleContext is the trade under check and the user whose account is
+// checked: the owner when the trade is stored, the accepting user when it is
+// accepted. Data the rules need from the database is loaded on first use, so
+// rules that are not configured cost nothing. pendingLimit is set by the
+// max_pending_trades rule for the repository to enforce.
+type tradeRuleContext struct {
+	data         db.TradeData
+	userID       uuid.UUID
+	accepting    bool
+	items        map[uuid.UUID]db.ItemData
+	joined       *time.Time
+	pendingLimit int
+}
+
+func (c *tradeRuleContext) lines() []db.TradeItem {
+	return append(append([]db.TradeItem{}, c.data.OfferedItems...), c.data.RequestedItems...)
+}
+
+func (c *tradeRuleContext) loadItems() (map[uuid.UUID]db.ItemData, error) {
+	if c.items != nil {
+		return c.items, nil
+	}
+
+	repo := db.NewRepositoryItem(logging.GetLogger())
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	var ids []uuid.UUID
+	for _, line := range c.lines() {
+		ids = append(ids, line.ItemID)
+	}
+	data, err := repo.FindByIDs(context.TODO(), ids)
+	if err != nil {
+		return nil, err
+	}
+
+	c.items = make(map[uuid.UUID]db.ItemData, len(data))
+	for _, itm := range data {
+		c.items[itm.ItemId] = itm
+	}
+	return c.items, nil
+}
+
+func (c *tradeRuleContext) accountCreatedAt() (time.Time, error) {
+	if c.joined != nil {
+		return *c.joined, nil
+	}
+
+	repo := db.NewRepositoryUser(logging.GetLogger())
+	if repo == nil {
+		return time.Time{}, fmt.Errorf("failed to create repository")
+	}
+
+	joined, err := repo.FindCreatedAt(context.TODO(), c.userID.String())
+	if err != nil {
+		return time.Time{}, err
+	}
+	c.joined = &joined
+	return joined, nil
+}
+
+var (
+	tradeRulesOnce sync.Once
+	tradeRules     []tradeRule
+)
+
+// loadTradeRules builds the rules declared in config.yaml once. The rules are
+// validated when the configuration is loaded.
+func loadTradeRules() []tradeRule {
+	tradeRulesOnce.Do(func() {
+		for _, cfg := range config.GetConfig().TradeRules {
+			tradeRules = append(tradeRules, newTradeRule(cfg))
+		}
+	})
+	return tradeRules
+}
+
+func newTradeRule(cfg config.TradeRuleConfig) tradeRule {
+	switch cfg.Rule {
+	case TradeRuleMaxItemsPerSide:
+		return onStore(maxItemsPerSide(cfg.Limit))
+	case TradeRuleNoItemOnBothSides:
+		return onStore(noItemOnBothSides)
+	case TradeRuleMinAccountAge:
+		return minAccountAgeForRarity(slices.Index(db.ItemRarities, strings.ToLower(cfg.Rarity)), cfg.MinAge)
+	case TradeRuleMaxPendingTrades:
+		return onStore(maxPendingTrades(cfg.Limit))
+	default:
+		panic(fmt.Sprintf("unknown trade rule %q", cfg.Rule))
+	}
+}
+
+// onStore restricts rule to trades being stored: the shape of a trade and
+// the number of trades of its owner do not change when it is accepted.
+func onStore(rule tradeRule) tradeRule {
+	return func(c *tradeRuleContext) ([]RuleViolation, error) {
+		if c.accepting {
+			return nil, nil
+		}
+		return rule(c)
+	}
+}
+
+// maxItemsPerSide limits the number of item copies on each side of a trade.
+func maxItemsPerSide(limit int) tradeRule {
+	return func(c *tradeRuleContext) ([]RuleViolation, error) {
+		var violations []RuleViolation
+		sides := map[string][]db.TradeItem{
+			"offered":   c.data.OfferedItems,
+			"requested": c.data.RequestedItems,
+		}
+		for _, side := range []string{"offered", "requested"} {
+			total := 0
+			for _, line := range sides[side] {
+				total += line.Quantity
+			}
+			if total > limit {
+				violations = append(violations, RuleViolation{
+					Rule:    TradeRuleMaxItemsPerSide,
+					Message: fmt.Sprintf("%d %s items, at most %d are allowed per side", total, side, limit),
+				})
+			}
+		}
+		return violations, nil
+	}
+}
+
+// noItemOnBothSides forbids offering and requesting the same item.
+func noItemOnBothSides(c *tradeRuleContext) ([]RuleViolation, error) {
+	offered := make(map[uuid.UUID]bool, len(c.data.OfferedItems))
+	for _, line := range c.data.OfferedItems {
+		offered[line.ItemID] = true
+	}
+
+	var violations []RuleViolation
+	for _, line := range c.data.RequestedItems {
+		if offered[line.ItemID] {
+			itemID := line.ItemID
+			violations = append(violations, RuleViolation{
+				Rule:    TradeRuleNoItemOnBothSides,
+				Message: fmt.Sprintf("item %s is both offered and requested", itemID),
+				ItemID:  &itemID,
+			})
+		}
+	}
+	return violations, nil
+}
+
+// minAccountAgeForRarity keeps accounts younger than minAge from trading
+// items rarer than the rarity ranked rank in db.ItemRarities, whether they
+// create the trade or accept it.
+func minAccountAgeForRarity(rank int, minAge time.Duration) tradeRule {
+	return func(c *tradeRuleContext) ([]RuleViolation, error) {
+		items, err := c.loadItems()
+		if err != nil {
+			return nil, err
+		}
+
+		var rare []db.TradeItem
+		for _, line := range c.lines() {
+			if slices.Index(db.ItemRarities, strings.ToLower(items[line.ItemID].Rarity)) > rank {
+				rare = append(rare, line)
+			}
+		}
+		if len(rare) == 0 {
+			return nil, nil
+		}
+
+		joined, err := c.accountCreatedAt()
+		if err != nil {
+			return nil, err
+		}
+		if time.Since(joined) >= minAge {
+			return nil, nil
+		}
+
+		violations := make([]RuleViolation, 0, len(rare))
+		for _, line := range rare {
+			itemID := line.ItemID
+			violations = append(violations, RuleViolation{
+				Rule: TradeRuleMinAccountAge,
+				Message: fmt.Sprintf("%s item %s may only be traded by accounts older than %s",
+					items[itemID].Rarity, itemID, minAge),
+				ItemID: &itemID,
+			})
+		}
+		return violations, nil
+	}
+}
+
+// maxPendingTrades limits the number of pending trades a user has created.
+// The trades are counted by the repository when the new one is inserted,
+// under a lock on the user, so concurrent creates cannot exceed the limit.
+func maxPendingTrades(limit int) tradeRule {
+	return func(c *tradeRuleContext) ([]RuleViolation, error) {
+		if c.data.TradeID == uuid.Nil {
+			c.pendingLimit = limit
+		}
+		return nil, nil
+	}
+}
+
+// checkTradeRules evaluates every configured rule against a trade about to
+// be created or updated and returns a *TradeRuleError listing all
+// violations. Limits enforced when the trade is inserted are set on data.
+func checkTradeRules(data *db.TradeData) error {
+	c := &tradeRuleContext{data: *data, userID: data.UserID}
+	if err := runTradeRules(c); err != nil {
+		return err
+	}
+	data.PendingLimit = c.pendingLimit
+	return nil
+}
+
+// checkAcceptRules evaluates the rules that also bind the user accepting a
+// trade, see onStore.
+func checkAcceptRules(data db.TradeData, userID uuid.UUID) error {
+	return runTradeRules(&tradeRuleContext{data: data, userID: userID, accepting: true})
+}
+
+func runTradeRules(c *tradeRuleContext) error {
+	var violations []RuleViolation
+	for _, rule := range loadTradeRules() {
+		found, err := rule(c)
+		if err != nil {
+			return err
+		}
+		violations = append(violations, found...)
+	}
+
+	if len(violations) > 0 {
+		return &TradeRuleError{Violations: violations}
+	}
+	return nil
+}
+
+// pendingLimitViolation turns the limit the repository enforced into the
+// violation of the max_pending_trades rule, or returns err unchanged.
+func pendingLimitViolation(err error) error {
+	var limitErr *db.PendingLimitError
+	if !errors.As(err, &limitErr) {
+		return err
+	}
+	return &TradeRuleError{Violations: []RuleViolation{{
+		Rule:    TradeRuleMaxPendingTrades,
+		Message: limitErr.Error(),
+	}}}
+}
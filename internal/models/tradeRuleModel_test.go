@@ -0,0 +1,158 @@
+package model
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+)
+
+func ruleNames(violations []RuleViolation) []string {
+	names := make([]string, 0, len(violations))
+	for _, v := range violations {
+		names = append(names, v.Rule)
+	}
+	return names
+}
+
+func TestMaxItemsPerSide(t *testing.T) {
+	hook, blade := uuid.New(), uuid.New()
+	side := func(status string, quantities ...int) []db.TradeItem {
+		items := make([]db.TradeItem, 0, len(quantities))
+		for i, q := range quantities {
+			id := hook
+			if i%2 == 1 {
+				id = blade
+			}
+			items = append(items, db.TradeItem{ItemID: id, ItemStatus: status, Quantity: q})
+		}
+		return items
+	}
+
+	tests := []struct {
+		name      string
+		offered   []db.TradeItem
+		requested []db.TradeItem
+		want      int
+	}{
+		{"within the limit", side("offered", 2, 1), side("requested", 3), 0},
+		{"copies count against the limit", side("offered", 4), side("requested", 1), 1},
+		{"both sides over the limit", side("offered", 2, 2), side("requested", 3, 1), 2},
+	}
+
+	rule := maxItemsPerSide(3)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &tradeRuleContext{data: db.TradeData{OfferedItems: tt.offered, RequestedItems: tt.requested}}
+			violations, err := rule(c)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(violations) != tt.want {
+				t.Errorf("got %d violations, want %d", len(violations), tt.want)
+			}
+		})
+	}
+}
+
+func TestNoItemOnBothSides(t *testing.T) {
+	hook, blade := uuid.New(), uuid.New()
+	c := &tradeRuleContext{data: db.TradeData{
+		OfferedItems:   []db.TradeItem{{ItemID: hook}, {ItemID: blade}},
+		RequestedItems: []db.TradeItem{{ItemID: blade}},
+	}}
+
+	violations, err := noItemOnBothSides(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 1 || violations[0].ItemID == nil || *violations[0].ItemID != blade {
+		t.Errorf("got %+v, want a single violation for item %s", violations, blade)
+	}
+}
+
+func TestMinAccountAgeForRarity(t *testing.T) {
+	common, arcana := uuid.New(), uuid.New()
+	items := map[uuid.UUID]db.ItemData{
+		common: {ItemId: common, Rarity: "Common"},
+		arcana: {ItemId: arcana, Rarity: "Arcana"},
+	}
+	rule := minAccountAgeForRarity(slices.Index(db.ItemRarities, "mythical"), 7*24*time.Hour)
+
+	tests := []struct {
+		name   string
+		item   uuid.UUID
+		joined time.Time
+		want   int
+	}{
+		{"new account, common item", common, time.Now(), 0},
+		{"new account, rare item", arcana, time.Now().Add(-time.Hour), 1},
+		{"old account, rare item", arcana, time.Now().Add(-30 * 24 * time.Hour), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			joined := tt.joined
+			c := &tradeRuleContext{
+				data:   db.TradeData{RequestedItems: []db.TradeItem{{ItemID: tt.item, Quantity: 1}}},
+				items:  items,
+				joined: &joined,
+			}
+			violations, err := rule(c)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(violations) != tt.want {
+				t.Errorf("got violations %v, want %d", ruleNames(violations), tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxPendingTrades(t *testing.T) {
+	rule := maxPendingTrades(5)
+
+	created := &tradeRuleContext{}
+	if _, err := rule(created); err != nil || created.pendingLimit != 5 {
+		t.Errorf("new trade: pendingLimit = %d, err = %v, want 5", created.pendingLimit, err)
+	}
+
+	updated := &tradeRuleContext{data: db.TradeData{TradeID: uuid.New()}}
+	if _, err := rule(updated); err != nil || updated.pendingLimit != 0 {
+		t.Errorf("updated trade: pendingLimit = %d, err = %v, want 0", updated.pendingLimit, err)
+	}
+}
+
+func TestOnStore(t *testing.T) {
+	always := func(c *tradeRuleContext) ([]RuleViolation, error) {
+		return []RuleViolation{{Rule: "always"}}, nil
+	}
+	rule := onStore(always)
+
+	if violations, _ := rule(&tradeRuleContext{}); len(violations) != 1 {
+		t.Errorf("store: got %d violations, want 1", len(violations))
+	}
+	if violations, _ := rule(&tradeRuleContext{accepting: true}); len(violations) != 0 {
+		t.Errorf("accept: got %d violations, want 0", len(violations))
+	}
+}
+
+func TestPendingLimitViolation(t *testing.T) {
+	other := errors.New("connection refused")
+	if err := pendingLimitViolation(other); err != other {
+		t.Errorf("unrelated error changed to %v", err)
+	}
+
+	err := pendingLimitViolation(&db.PendingLimitError{Pending: 5, Limit: 5})
+	var ruleErr *TradeRuleError
+	if !errors.As(err, &ruleErr) || !errors.Is(err, ErrTradeRuleViolation) {
+		t.Fatalf("expected a TradeRuleError, got %v", err)
+	}
+	if names := ruleNames(ruleErr.Violations); len(names) != 1 || names[0] != TradeRuleMaxPendingTrades {
+		t.Errorf("got violations %v, want [%s]", names, TradeRuleMaxPendingTrades)
+	}
+}
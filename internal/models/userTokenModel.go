@@ -27,6 +27,8 @@ type Token struct {
 	UserRole       string    `json:"user_role"`
 }
 
+type tokenContextKey struct{}
+
 type TokenClaims struct {
 	jwt.StandardClaims
 	UserId    uuid.UUID `json:"user_id"`
@@ -147,6 +149,17 @@ func DeleteTokenByUserID(userID string) error {
 	return nil
 }
 
+// ContextWithToken returns a copy of ctx carrying the token of the authenticated user.
+func ContextWithToken(ctx context.Context, token *Token) context.Context {
+	return context.WithValue(ctx, tokenContextKey{}, token)
+}
+
+// TokenFromContext returns the token stored by ContextWithToken, if any.
+func TokenFromContext(ctx context.Context) (*Token, bool) {
+	token, ok := ctx.Value(tokenContextKey{}).(*Token)
+	return token, ok && token != nil
+}
+
 func ParseToken(accesstoken string) (*Token, error) {
 	token, err := jwt.ParseWithClaims(accesstoken, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/config"
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+const (
+	defaultWalletHistoryLimit = 50
+	maxWalletHistoryLimit     = 200
+)
+
+var (
+	ErrInsufficientFunds   = db.ErrInsufficientFunds
+	ErrInvalidWalletQuery  = errors.New("invalid wallet query")
+	ErrInvalidWalletAdjust = errors.New("invalid wallet adjustment")
+)
+
+// Wallet shows the balances of a user per currency together with the latest
+// movements on the user's ledger accounts.
+type Wallet struct {
+	UserID       uuid.UUID            `json:"user_id"`
+	Balances     []db.WalletBalance   `json:"balances"`
+	Transactions []db.LedgerEntryData `json:"transactions"`
+}
+
+// WalletAdjustment is a simulated deposit (positive amount) or withdrawal
+// (negative amount) made by an admin.
+type WalletAdjustment struct {
+	Amount   float64 `json:"amount" validate:"required"`
+	Currency string  `json:"currency" validate:"omitempty,min=3,max=10"`
+	Memo     string  `json:"memo" validate:"max=500"`
+}
+
+// LoadWallet returns the balances of the user and up to limit of the latest
+// ledger entries, newest first.
+func LoadWallet(userID uuid.UUID, limit int) (*Wallet, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryWallet(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	switch {
+	case limit == 0:
+		limit = defaultWalletHistoryLimit
+	case limit < 0 || limit > maxWalletHistoryLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidWalletQuery, maxWalletHistoryLimit)
+	}
+
+	balances, err := repo.FindBalances(context.TODO(), userID.String())
+	if err != nil {
+		logger.Infof("Failed to load wallet balances: %v", err)
+		return nil, err
+	}
+
+	entries, err := repo.FindEntries(context.TODO(), userID.String(), limit)
+	if err != nil {
+		logger.Infof("Failed to load wallet history: %v", err)
+		return nil, err
+	}
+
+	return &Wallet{UserID: userID, Balances: balances, Transactions: entries}, nil
+}
+
+// AdjustWallet credits or debits the wallet of userID on behalf of an admin
+// and returns the updated wallet. The currency defaults to the market one.
+func AdjustWallet(userID uuid.UUID, adj WalletAdjustment, actor *Token) (*Wallet, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryWallet(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	if adj.Currency == "" {
+		adj.Currency = config.GetConfig().Market.DefaultCurrency
+	}
+	if adj.Amount > -0.005 && adj.Amount < 0.005 {
+		return nil, fmt.Errorf("%w: amount must be at least 0.01", ErrInvalidWalletAdjust)
+	}
+
+	if _, err := repo.Adjust(context.TODO(), userID, adj.Amount, adj.Currency, adj.Memo, actor.UserID); err != nil {
+		logger.Infof("Failed to adjust wallet: %v", err)
+		return nil, err
+	}
+
+	return LoadWallet(userID, 0)
+}
@@ -0,0 +1,111 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/repositories/db"
+	"go-server/pkg/logging"
+)
+
+var (
+	ErrItemNotFound          = db.ErrItemNotFound
+	ErrWishlistEntryNotFound = db.ErrWishlistEntryNotFound
+	ErrInvalidWishlistEntry  = errors.New("invalid wishlist entry")
+)
+
+// WishlistEntry is an item a user wants to be notified about when it is
+// offered in a new trade. MaxRarity and Quality optionally narrow which
+// offers are of interest.
+type WishlistEntry struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ItemID    uuid.UUID `json:"item_id" validate:"required"`
+	MaxRarity string    `json:"max_rarity,omitempty"`
+	Quality   string    `json:"quality,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (e *WishlistEntry) Save() error {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryWishlist(logger)
+
+	if repo == nil {
+		return fmt.Errorf("failed to create repository")
+	}
+
+	data := db.WishlistData{
+		UserID: e.UserID,
+		ItemID: e.ItemID,
+	}
+	if e.MaxRarity != "" {
+		rarity := strings.ToLower(e.MaxRarity)
+		if !slices.Contains(db.ItemRarities, rarity) {
+			return fmt.Errorf("%w: unknown rarity %q", ErrInvalidWishlistEntry, e.MaxRarity)
+		}
+		data.MaxRarity = &rarity
+	}
+	if e.Quality != "" {
+		data.Quality = &e.Quality
+	}
+
+	saved, err := repo.Upsert(context.TODO(), data)
+	if err != nil {
+		logger.Infof("Failed to save wishlist entry: %v", err)
+		return err
+	}
+	e.CreatedAt = saved.CreatedAt
+	return nil
+}
+
+func LoadWishlist(userID string) ([]*WishlistEntry, error) {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryWishlist(logger)
+
+	if repo == nil {
+		return nil, fmt.Errorf("failed to create repository")
+	}
+
+	data, err := repo.FindByUserID(context.TODO(), userID)
+	if err != nil {
+		logger.Infof("Failed to load wishlist: %v", err)
+		return []*WishlistEntry{}, err
+	}
+
+	entries := make([]*WishlistEntry, 0, len(data))
+	for _, w := range data {
+		entry := &WishlistEntry{
+			UserID:    w.UserID,
+			ItemID:    w.ItemID,
+			CreatedAt: w.CreatedAt,
+		}
+		if w.MaxRarity != nil {
+			entry.MaxRarity = *w.MaxRarity
+		}
+		if w.Quality != nil {
+			entry.Quality = *w.Quality
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func RemoveWishlistEntry(userID, itemID string) error {
+	logger := logging.GetLogger()
+	repo := db.NewRepositoryWishlist(logger)
+
+	if repo == nil {
+		return fmt.Errorf("failed to create repository")
+	}
+
+	if err := repo.Delete(context.TODO(), userID, itemID); err != nil {
+		logger.Infof("Failed to remove wishlist entry: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Package receipt signs trade receipts with the Ed25519 key from the
+// RECEIPT_SIGNING_KEY environment variable, so that anyone holding the public
+// key can verify them offline.
+package receipt
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go-server/internal/config"
+)
+
+const Algorithm = "Ed25519"
+
+var ErrNoSigningKey = errors.New("receipt signing key is not configured")
+
+var (
+	once    sync.Once
+	key     ed25519.PrivateKey
+	keyID   string
+	loadErr error
+)
+
+// load reads the signing key from the configuration once.
+func load() error {
+	once.Do(func() {
+		encoded := config.GetConfig().Receipts.SigningKey
+		if encoded == "" {
+			loadErr = ErrNoSigningKey
+			return
+		}
+
+		key, loadErr = parseKey(encoded)
+		if loadErr != nil {
+			return
+		}
+		keyID = KeyID(key.Public().(ed25519.PublicKey))
+	})
+	return loadErr
+}
+
+// parseKey decodes a signing key given as the base64 encoded 32 bytes seed.
+func parseKey(encoded string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid receipt signing key: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid receipt signing key: want a %d bytes seed, got %d bytes", ed25519.SeedSize, len(seed))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// KeyID identifies a public key by the first 8 bytes of its SHA-256 digest.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// PublicKey returns the public half of the signing key and its ID.
+func PublicKey() (ed25519.PublicKey, string, error) {
+	if err := load(); err != nil {
+		return nil, "", err
+	}
+	return key.Public().(ed25519.PublicKey), keyID, nil
+}
+
+// Sign returns the signature of payload and the ID of the signing key.
+func Sign(payload []byte) ([]byte, string, error) {
+	if err := load(); err != nil {
+		return nil, "", err
+	}
+	return ed25519.Sign(key, payload), keyID, nil
+}
+
+// Canonical encodes v as canonical JSON: without insignificant whitespace,
+// object keys sorted and numbers kept as encoded. Receipts are signed in this
+// form, so the signed bytes do not depend on struct field order.
+func Canonical(v any) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(generic)
+}
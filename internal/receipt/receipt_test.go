@@ -0,0 +1,110 @@
+package receipt
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+)
+
+// testSeed is a fixed seed so that the signatures below are reproducible.
+var testSeed = bytes.Repeat([]byte{7}, ed25519.SeedSize)
+
+func init() {
+	// Consume once with the test key, so Sign and PublicKey never read the
+	// configuration.
+	once.Do(func() {
+		key = ed25519.NewKeyFromSeed(testSeed)
+		keyID = KeyID(key.Public().(ed25519.PublicKey))
+	})
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		wantErr bool
+	}{
+		{"valid seed", base64.StdEncoding.EncodeToString(testSeed), false},
+		{"not base64", "not a key!", true},
+		{"short seed", base64.StdEncoding.EncodeToString(testSeed[:16]), true},
+		{"full private key", base64.StdEncoding.EncodeToString(ed25519.NewKeyFromSeed(testSeed)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseKey(tt.encoded)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(ed25519.NewKeyFromSeed(testSeed)) {
+				t.Error("parsed key does not match the seed")
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	type line struct {
+		Quantity int    `json:"quantity"`
+		ItemID   string `json:"item_id"`
+	}
+
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{"keys are sorted", map[string]any{"b": 1, "a": 2}, `{"a":2,"b":1}`},
+		{"struct fields are sorted", line{Quantity: 2, ItemID: "hook"}, `{"item_id":"hook","quantity":2}`},
+		{"nested objects are sorted", map[string]any{"z": []line{{1, "x"}}, "a": nil}, `{"a":null,"z":[{"item_id":"x","quantity":1}]}`},
+		{"numbers are kept as encoded", map[string]any{"price": 12345678901234567.0, "fee": 0.1}, `{"fee":0.1,"price":12345678901234568}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonical(tt.v)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	payload, err := Canonical(map[string]any{"trade_id": "42", "status": "completed"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	signature, id, err := Sign(payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pub, pubID, err := PublicKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != pubID || id != KeyID(pub) {
+		t.Errorf("key IDs differ: signed with %s, public key %s", id, pubID)
+	}
+	if !ed25519.Verify(pub, payload, signature) {
+		t.Error("signature does not verify")
+	}
+
+	tampered := append([]byte{}, payload...)
+	tampered[len(tampered)-2] = 'X'
+	if ed25519.Verify(pub, tampered, signature) {
+		t.Error("signature verifies a tampered receipt")
+	}
+}
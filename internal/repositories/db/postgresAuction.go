@@ -0,0 +1,652 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+var (
+	ErrAuctionNotFound = errors.New("auction not found")
+	// ErrAuctionNotActive is returned when an auction has ended, was cancelled
+	// or its end time has passed.
+	ErrAuctionNotActive = errors.New("auction is not active")
+	ErrAuctionHasBids   = errors.New("auction already has bids")
+	ErrBidTooLow        = errors.New("bid is too low")
+	ErrOwnAuctionBid    = errors.New("seller cannot bid on own auction")
+	// ErrAuctionTrade is returned when the items of the trade created for an
+	// auction winner are changed: the winning bid pays for the auctioned item.
+	ErrAuctionTrade = errors.New("trade settles an auction and cannot be changed")
+)
+
+// auctionSelect is the projection shared by all queries returning auctions.
+// Rows must be read with scanAuction.
+const auctionSelect = `
+		SELECT
+			a.id,
+			a.seller_id,
+			a.item_id,
+			i.name,
+			i.rarity,
+			i.quality,
+			a.start_price,
+			a.min_increment,
+			a.currency,
+			a.status,
+			a.current_bid,
+			a.high_bidder_id,
+			a.bid_count,
+			a.ends_at,
+			a.created_at,
+			a.closed_at,
+			a.trade_id
+		FROM public.auction a
+		JOIN public.item i ON i.id = a.item_id
+`
+
+type RepositoryAuction struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+type AuctionData struct {
+	ID           uuid.UUID  `json:"id"`
+	SellerID     uuid.UUID  `json:"seller_id"`
+	ItemID       uuid.UUID  `json:"item_id"`
+	Name         string     `json:"name"`
+	Rarity       string     `json:"rarity"`
+	Quality      string     `json:"quality,omitempty"`
+	StartPrice   float64    `json:"start_price"`
+	MinIncrement float64    `json:"min_increment"`
+	Currency     string     `json:"currency"`
+	Status       string     `json:"status"`
+	CurrentBid   *float64   `json:"current_bid,omitempty"`
+	HighBidderID *uuid.UUID `json:"high_bidder_id,omitempty"`
+	BidCount     int        `json:"bid_count"`
+	EndsAt       time.Time  `json:"ends_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+	TradeID      *uuid.UUID `json:"trade_id,omitempty"`
+}
+
+type AuctionBidData struct {
+	ID        uuid.UUID `json:"id"`
+	AuctionID uuid.UUID `json:"auction_id"`
+	BidderID  uuid.UUID `json:"bidder_id"`
+	Amount    float64   `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuctionFilter narrows the auctions returned by FindAll. Zero values disable
+// the corresponding condition.
+type AuctionFilter struct {
+	Status   string
+	SellerID uuid.UUID
+	ItemID   uuid.UUID
+}
+
+func NewRepositoryAuction(logger *logging.Logger) *RepositoryAuction {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryAuction{
+		client: client,
+		logger: logger,
+	}
+}
+
+// Create stores an active auction and puts one free instance of the item
+// from the seller's inventory in escrow. An empty currency falls back to the
+// currency of the item's market price, then to defaultCurrency.
+func (r *RepositoryAuction) Create(ctx context.Context, data AuctionData, defaultCurrency string) (_ uuid.UUID, err error) {
+	q := `
+		INSERT INTO public.auction (
+			id,
+			seller_id,
+			item_id,
+			start_price,
+			min_increment,
+			currency,
+			status,
+			ends_at,
+			created_at
+		)
+		VALUES (
+			gen_random_uuid(),
+			$1,
+			$2,
+			$3,
+			$4,
+			COALESCE(
+				NULLIF($5, ''),
+				(SELECT p.currency FROM public.item_price p JOIN public.item i ON i.name = p.name WHERE i.id = $2),
+				$6
+			),
+			'active',
+			$7,
+			CURRENT_TIMESTAMP
+		)
+		RETURNING id
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	if err = tx.QueryRow(ctx, q, data.SellerID, data.ItemID, data.StartPrice, data.MinIncrement, data.Currency, defaultCurrency, data.EndsAt).Scan(&data.ID); err != nil {
+		return uuid.Nil, priceViolation(err)
+	}
+
+	if err = reserveAuctionItem(ctx, tx, data.ID, data.SellerID, data.ItemID); err != nil {
+		r.logger.Infof("Failed to reserve auctioned item: %v", err)
+		return uuid.Nil, err
+	}
+
+	r.logger.Infof("Completed to create auction: %v", data)
+	return data.ID, nil
+}
+
+func (r *RepositoryAuction) FindOne(ctx context.Context, auctionID string) (AuctionData, error) {
+	q := auctionSelect + `
+		WHERE
+			a.id = $1
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	a, err := scanAuction(r.client.QueryRow(ctx, q, auctionID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return AuctionData{}, ErrAuctionNotFound
+	}
+	if err != nil {
+		return AuctionData{}, err
+	}
+
+	return a, nil
+}
+
+// FindAll returns the auctions matching the filter. Active auctions ending
+// soonest come first, then the others newest first.
+func (r *RepositoryAuction) FindAll(ctx context.Context, filter AuctionFilter) ([]AuctionData, error) {
+	var conds []string
+	var args []any
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conds = append(conds, fmt.Sprintf("a.status = $%d", len(args)))
+	}
+	if filter.SellerID != uuid.Nil {
+		args = append(args, filter.SellerID)
+		conds = append(conds, fmt.Sprintf("a.seller_id = $%d", len(args)))
+	}
+	if filter.ItemID != uuid.Nil {
+		args = append(args, filter.ItemID)
+		conds = append(conds, fmt.Sprintf("a.item_id = $%d", len(args)))
+	}
+
+	cond := "TRUE"
+	if len(conds) > 0 {
+		cond = strings.Join(conds, " AND ")
+	}
+
+	q := auctionSelect + fmt.Sprintf(`
+		WHERE
+			%s
+		ORDER BY a.status <> 'active', CASE WHEN a.status = 'active' THEN a.ends_at END, a.created_at DESC, a.id DESC
+	`, cond)
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	auctions := make([]AuctionData, 0)
+	for rows.Next() {
+		a, err := scanAuction(rows)
+		if err != nil {
+			return nil, err
+		}
+		auctions = append(auctions, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return auctions, nil
+}
+
+// FindBids returns the bids placed on an auction, highest first.
+func (r *RepositoryAuction) FindBids(ctx context.Context, auctionID uuid.UUID) ([]AuctionBidData, error) {
+	q := `
+		SELECT
+			id,
+			auction_id,
+			bidder_id,
+			amount,
+			created_at
+		FROM public.auction_bid
+		WHERE
+			auction_id = $1
+		ORDER BY amount DESC, created_at
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	bids, err := pgx.CollectRows(rows, pgx.RowToStructByPos[AuctionBidData])
+	if err != nil {
+		return nil, err
+	}
+
+	return bids, nil
+}
+
+// PlaceBid records a bid from bidderID. The bid must be at least the start
+// price, or the current bid plus the minimum increment. A bid placed less
+// than window before the end pushes the end back to extension from now.
+// The bid amount is held in the bidder's escrow and the outbid amount is
+// returned to the previous high bidder.
+func (r *RepositoryAuction) PlaceBid(ctx context.Context, auctionID, bidderID uuid.UUID, amount float64, window, extension time.Duration) (err error) {
+	q := `
+		SELECT
+			seller_id,
+			high_bidder_id,
+			current_bid,
+			currency,
+			status = 'active' AND ends_at > CURRENT_TIMESTAMP,
+			COALESCE(current_bid + min_increment, start_price),
+			$2::numeric >= COALESCE(current_bid + min_increment, start_price)
+		FROM public.auction
+		WHERE
+			id = $1
+		FOR UPDATE
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	var sellerID uuid.UUID
+	var highBidderID *uuid.UUID
+	var currentBid *float64
+	var currency string
+	var active, enough bool
+	var minimum float64
+	err = tx.QueryRow(ctx, q, auctionID, amount).Scan(&sellerID, &highBidderID, &currentBid, &currency, &active, &minimum, &enough)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrAuctionNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !active:
+		return ErrAuctionNotActive
+	case sellerID == bidderID:
+		return ErrOwnAuctionBid
+	case !enough:
+		return fmt.Errorf("%w: minimum bid is %.2f", ErrBidTooLow, minimum)
+	}
+
+	q = `
+		INSERT INTO public.auction_bid (
+			id,
+			auction_id,
+			bidder_id,
+			amount,
+			created_at
+		)
+		VALUES (
+			gen_random_uuid(),
+			$1,
+			$2,
+			$3,
+			CURRENT_TIMESTAMP
+		)
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	if _, err = tx.Exec(ctx, q, auctionID, bidderID, amount); err != nil {
+		return err
+	}
+
+	q = `
+		UPDATE public.auction
+		SET
+			current_bid = $2,
+			high_bidder_id = $3,
+			bid_count = bid_count + 1,
+			ends_at = CASE
+				WHEN ends_at - CURRENT_TIMESTAMP < $4 * interval '1 second'
+				THEN GREATEST(ends_at, CURRENT_TIMESTAMP + $5 * interval '1 second')
+				ELSE ends_at
+			END
+		WHERE
+			id = $1
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	if _, err = tx.Exec(ctx, q, auctionID, amount, bidderID, window.Seconds(), extension.Seconds()); err != nil {
+		return err
+	}
+
+	// Release the outbid amount first so a bidder raising their own bid only
+	// needs the difference in their wallet.
+	if highBidderID != nil {
+		_, err = postLedger(ctx, tx, ledgerTransfer{
+			Kind:        LedgerBidRelease,
+			Currency:    currency,
+			ReferenceID: &auctionID,
+			CreatedBy:   &bidderID,
+			Postings: []ledgerPosting{
+				{UserID: *highBidderID, Account: AccountEscrow, Amount: -*currentBid},
+				{UserID: *highBidderID, Account: AccountWallet, Amount: *currentBid},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = postLedger(ctx, tx, ledgerTransfer{
+		Kind:        LedgerBidHold,
+		Currency:    currency,
+		ReferenceID: &auctionID,
+		CreatedBy:   &bidderID,
+		Postings: []ledgerPosting{
+			{UserID: bidderID, Account: AccountWallet, Amount: -amount},
+			{UserID: bidderID, Account: AccountEscrow, Amount: amount},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Cancel cancels an active auction that has no bids yet and returns the item
+// held in escrow to the seller.
+func (r *RepositoryAuction) Cancel(ctx context.Context, auctionID uuid.UUID) (err error) {
+	q := `
+		UPDATE public.auction
+		SET
+			status = 'cancelled',
+			closed_at = CURRENT_TIMESTAMP
+		WHERE
+			id = $1
+		AND
+			status = 'active'
+		AND
+			bid_count = 0
+		RETURNING id
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	tag, err := tx.Exec(ctx, q, auctionID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		var status string
+		var bids int
+		err = tx.QueryRow(ctx, `SELECT status, bid_count FROM public.auction WHERE id = $1`, auctionID).Scan(&status, &bids)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return ErrAuctionNotFound
+		case err != nil:
+			return err
+		case status != "active":
+			return ErrAuctionNotActive
+		default:
+			return ErrAuctionHasBids
+		}
+	}
+
+	if err = releaseAuctionItem(ctx, tx, auctionID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindEnded returns up to limit active auctions whose end time has passed,
+// oldest first.
+func (r *RepositoryAuction) FindEnded(ctx context.Context, limit int) ([]uuid.UUID, error) {
+	q := `
+		SELECT
+			id
+		FROM public.auction
+		WHERE
+			status = 'active'
+		AND
+			ends_at <= CURRENT_TIMESTAMP
+		ORDER BY ends_at, id
+		LIMIT $1
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// Close ends an auction whose end time has passed and returns the item held
+// in escrow to the seller. When the auction had a winner, a private pending
+// trade offering the item to the highest bidder is created in the same
+// transaction; the winning bid stays in escrow until that trade is settled,
+// see settleAuctionTrade. Auctions locked by a concurrent close are skipped
+// and reported as not closed.
+func (r *RepositoryAuction) Close(ctx context.Context, auctionID uuid.UUID, tradeExpiresAt time.Time) (_ *uuid.UUID, closed bool, err error) {
+	q := `
+		SELECT
+			seller_id,
+			item_id,
+			high_bidder_id
+		FROM public.auction
+		WHERE
+			id = $1
+		AND
+			status = 'active'
+		AND
+			ends_at <= CURRENT_TIMESTAMP
+		FOR UPDATE SKIP LOCKED
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	var sellerID, itemID uuid.UUID
+	var winnerID *uuid.UUID
+	err = tx.QueryRow(ctx, q, auctionID).Scan(&sellerID, &itemID, &winnerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err = releaseAuctionItem(ctx, tx, auctionID); err != nil {
+		return nil, false, err
+	}
+
+	var tradeID *uuid.UUID
+	if winnerID != nil {
+		trades := &RepositoryTrade{client: r.client, logger: r.logger}
+		id, err := trades.insertTrade(ctx, tx, TradeData{
+			UserID:       sellerID,
+			RecipientID:  winnerID,
+			Status:       "pending",
+			ExpiresAt:    &tradeExpiresAt,
+			OfferedItems: []TradeItem{{ItemID: itemID, ItemStatus: "offered", Quantity: 1}},
+		})
+		if err != nil {
+			r.logger.Errorf("Failed to create trade for auction %s: %v", auctionID, err)
+			return nil, false, err
+		}
+		tradeID = &id
+	}
+
+	q = `
+		UPDATE public.auction
+		SET
+			status = 'ended',
+			closed_at = CURRENT_TIMESTAMP,
+			trade_id = $2
+		WHERE
+			id = $1
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	if _, err = tx.Exec(ctx, q, auctionID, tradeID); err != nil {
+		return nil, false, err
+	}
+
+	return tradeID, true, nil
+}
+
+// settleAuctionTrade pays the seller the winning bid held in escrow when the
+// trade created for an auction winner completes, or refunds the winner when
+// the trade is rejected, cancelled, expired or deleted. Trades that do not
+// come from an auction and auctions already settled are left alone.
+func settleAuctionTrade(ctx context.Context, tx pgx.Tx, tradeID uuid.UUID, paid bool) error {
+	settlement := "refunded"
+	if paid {
+		settlement = "paid"
+	}
+
+	q := `
+		UPDATE public.auction
+		SET
+			settlement = $2
+		WHERE
+			trade_id = $1
+		AND
+			settlement IS NULL
+		AND
+			high_bidder_id IS NOT NULL
+		RETURNING id, seller_id, high_bidder_id, current_bid, currency
+	`
+
+	var a AuctionData
+	err := tx.QueryRow(ctx, q, tradeID, settlement).Scan(&a.ID, &a.SellerID, &a.HighBidderID, &a.CurrentBid, &a.Currency)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	transfer := ledgerTransfer{
+		Kind:        LedgerBidRelease,
+		Currency:    a.Currency,
+		ReferenceID: &a.ID,
+		Postings: []ledgerPosting{
+			{UserID: *a.HighBidderID, Account: AccountEscrow, Amount: -*a.CurrentBid},
+			{UserID: *a.HighBidderID, Account: AccountWallet, Amount: *a.CurrentBid},
+		},
+	}
+	if paid {
+		transfer.Kind = LedgerAuctionSale
+		transfer.Postings[1] = ledgerPosting{UserID: a.SellerID, Account: AccountWallet, Amount: *a.CurrentBid}
+	}
+
+	if _, err := postLedger(ctx, tx, transfer); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// auctionTrade reports whether the trade was created for an auction winner.
+func auctionTrade(ctx context.Context, tx pgx.Tx, tradeID uuid.UUID) (bool, error) {
+	q := `
+		SELECT EXISTS (
+			SELECT
+				1
+			FROM public.auction
+			WHERE
+				trade_id = $1
+		)
+	`
+
+	var exists bool
+	if err := tx.QueryRow(ctx, q, tradeID).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func scanAuction(row pgx.Row) (AuctionData, error) {
+	var a AuctionData
+	err := row.Scan(&a.ID, &a.SellerID, &a.ItemID, &a.Name, &a.Rarity, &a.Quality, &a.StartPrice, &a.MinIncrement, &a.Currency, &a.Status,
+		&a.CurrentBid, &a.HighBidderID, &a.BidCount, &a.EndsAt, &a.CreatedAt, &a.ClosedAt, &a.TradeID)
+	return a, err
+}
@@ -0,0 +1,505 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+var (
+	ErrBuyOrderNotFound = errors.New("buy order not found")
+	// ErrBuyOrderNotActive is returned when a buy order was already filled or
+	// cancelled, usually by a concurrent request.
+	ErrBuyOrderNotActive = errors.New("buy order is not active")
+)
+
+// buyOrderSelect is the projection shared by all queries returning buy
+// orders. Rows must be read with scanBuyOrder.
+const buyOrderSelect = `
+		SELECT
+			o.id,
+			o.buyer_id,
+			o.item_id,
+			i.name,
+			i.rarity,
+			o.price,
+			o.currency,
+			o.status,
+			o.listing_id,
+			o.fill_price,
+			o.created_at,
+			o.closed_at
+		FROM public.buy_order o
+		JOIN public.item i ON i.id = o.item_id
+`
+
+type RepositoryBuyOrder struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+type BuyOrderData struct {
+	ID        uuid.UUID  `json:"id"`
+	BuyerID   uuid.UUID  `json:"buyer_id"`
+	ItemID    uuid.UUID  `json:"item_id"`
+	Name      string     `json:"name"`
+	Rarity    string     `json:"rarity"`
+	Price     float64    `json:"price"`
+	Currency  string     `json:"currency"`
+	Status    string     `json:"status"`
+	ListingID *uuid.UUID `json:"listing_id,omitempty"`
+	FillPrice *float64   `json:"fill_price,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+}
+
+// BuyOrderFilter narrows the buy orders returned by FindAll. Zero values
+// disable the corresponding condition.
+type BuyOrderFilter struct {
+	Status  string
+	BuyerID uuid.UUID
+	ItemID  uuid.UUID
+}
+
+// OrderBookLevel aggregates the active orders of one side of the book at a
+// single price.
+type OrderBookLevel struct {
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency"`
+	Quantity int     `json:"quantity"`
+}
+
+func NewRepositoryBuyOrder(logger *logging.Logger) *RepositoryBuyOrder {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryBuyOrder{
+		client: client,
+		logger: logger,
+	}
+}
+
+// Create stores an active buy order, holds its price in the buyer's escrow
+// and immediately fills it from the cheapest active listing at or below its
+// price, if there is one. An empty currency falls back to the currency of
+// the item's market price, then to defaultCurrency.
+func (r *RepositoryBuyOrder) Create(ctx context.Context, data BuyOrderData, defaultCurrency string) (_ uuid.UUID, err error) {
+	q := `
+		INSERT INTO public.buy_order (
+			id,
+			buyer_id,
+			item_id,
+			price,
+			currency,
+			status,
+			created_at
+		)
+		VALUES (
+			gen_random_uuid(),
+			$1,
+			$2,
+			$3,
+			COALESCE(
+				NULLIF($4, ''),
+				(SELECT p.currency FROM public.item_price p JOIN public.item i ON i.name = p.name WHERE i.id = $2),
+				$5
+			),
+			'active',
+			CURRENT_TIMESTAMP
+		)
+		RETURNING id, currency
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	if err = tx.QueryRow(ctx, q, data.BuyerID, data.ItemID, data.Price, data.Currency, defaultCurrency).Scan(&data.ID, &data.Currency); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return uuid.Nil, fmt.Errorf("%w: %s", ErrItemNotFound, data.ItemID)
+		}
+		return uuid.Nil, priceViolation(err)
+	}
+
+	_, err = postLedger(ctx, tx, ledgerTransfer{
+		Kind:        LedgerBuyOrderHold,
+		Currency:    data.Currency,
+		ReferenceID: &data.ID,
+		CreatedBy:   &data.BuyerID,
+		Postings: []ledgerPosting{
+			{UserID: data.BuyerID, Account: AccountWallet, Amount: -data.Price},
+			{UserID: data.BuyerID, Account: AccountEscrow, Amount: data.Price},
+		},
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if err = r.matchListing(ctx, tx, data); err != nil {
+		r.logger.Infof("Failed to match buy order: %v", err)
+		return uuid.Nil, err
+	}
+
+	r.logger.Infof("Completed to create buy order: %v", data)
+	return data.ID, nil
+}
+
+func (r *RepositoryBuyOrder) FindOne(ctx context.Context, orderID string) (BuyOrderData, error) {
+	q := buyOrderSelect + `
+		WHERE
+			o.id = $1
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	o, err := scanBuyOrder(r.client.QueryRow(ctx, q, orderID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return BuyOrderData{}, ErrBuyOrderNotFound
+	}
+	if err != nil {
+		return BuyOrderData{}, err
+	}
+
+	return o, nil
+}
+
+// FindAll returns the buy orders matching the filter, newest first.
+func (r *RepositoryBuyOrder) FindAll(ctx context.Context, filter BuyOrderFilter) ([]BuyOrderData, error) {
+	var conds []string
+	var args []any
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conds = append(conds, fmt.Sprintf("o.status = $%d", len(args)))
+	}
+	if filter.BuyerID != uuid.Nil {
+		args = append(args, filter.BuyerID)
+		conds = append(conds, fmt.Sprintf("o.buyer_id = $%d", len(args)))
+	}
+	if filter.ItemID != uuid.Nil {
+		args = append(args, filter.ItemID)
+		conds = append(conds, fmt.Sprintf("o.item_id = $%d", len(args)))
+	}
+
+	cond := "TRUE"
+	if len(conds) > 0 {
+		cond = strings.Join(conds, " AND ")
+	}
+
+	q := buyOrderSelect + fmt.Sprintf(`
+		WHERE
+			%s
+		ORDER BY o.created_at DESC, o.id DESC
+	`, cond)
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]BuyOrderData, 0)
+	for rows.Next() {
+		o, err := scanBuyOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, o)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
+
+// Cancel closes an active buy order and returns the held funds to the
+// buyer's wallet.
+func (r *RepositoryBuyOrder) Cancel(ctx context.Context, orderID uuid.UUID) (err error) {
+	q := `
+		UPDATE public.buy_order
+		SET
+			status = 'cancelled',
+			closed_at = CURRENT_TIMESTAMP
+		WHERE
+			id = $1
+		AND
+			status = 'active'
+		RETURNING buyer_id, price, currency
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	var o BuyOrderData
+	err = tx.QueryRow(ctx, q, orderID).Scan(&o.BuyerID, &o.Price, &o.Currency)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrBuyOrderNotActive
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = postLedger(ctx, tx, ledgerTransfer{
+		Kind:        LedgerBuyOrderRelease,
+		Currency:    o.Currency,
+		ReferenceID: &orderID,
+		CreatedBy:   &o.BuyerID,
+		Postings: []ledgerPosting{
+			{UserID: o.BuyerID, Account: AccountEscrow, Amount: -o.Price},
+			{UserID: o.BuyerID, Account: AccountWallet, Amount: o.Price},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindOrderBook returns the active buy orders (bids, best price first) and
+// active listings (asks, cheapest first) of an item grouped by price.
+func (r *RepositoryBuyOrder) FindOrderBook(ctx context.Context, itemID uuid.UUID) (bids, asks []OrderBookLevel, err error) {
+	var exists bool
+	if err := r.client.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM public.item WHERE id = $1)`, itemID).Scan(&exists); err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		return nil, nil, fmt.Errorf("%w: %s", ErrItemNotFound, itemID)
+	}
+
+	q := `
+		SELECT
+			price,
+			currency,
+			count(*)
+		FROM public.buy_order
+		WHERE
+			item_id = $1
+		AND
+			status = 'active'
+		GROUP BY price, currency
+		ORDER BY currency, price DESC
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, itemID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if bids, err = pgx.CollectRows(rows, pgx.RowToStructByPos[OrderBookLevel]); err != nil {
+		return nil, nil, err
+	}
+
+	q = `
+		SELECT
+			price,
+			currency,
+			count(*)
+		FROM public.listing
+		WHERE
+			item_id = $1
+		AND
+			status = 'active'
+		GROUP BY price, currency
+		ORDER BY currency, price
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err = r.client.Query(ctx, q, itemID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if asks, err = pgx.CollectRows(rows, pgx.RowToStructByPos[OrderBookLevel]); err != nil {
+		return nil, nil, err
+	}
+
+	return bids, asks, nil
+}
+
+// matchListing fills a new buy order from the cheapest active listing of the
+// same item and currency priced at or below the order. Listings locked by a
+// concurrent match or purchase are skipped.
+func (r *RepositoryBuyOrder) matchListing(ctx context.Context, tx pgx.Tx, order BuyOrderData) error {
+	q := `
+		SELECT
+			id
+		FROM public.listing
+		WHERE
+			item_id = $1
+		AND
+			currency = $2
+		AND
+			price <= $3
+		AND
+			seller_id <> $4
+		AND
+			status = 'active'
+		ORDER BY price, created_at, id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	if err := lockOrderBook(ctx, tx, order.ItemID); err != nil {
+		return err
+	}
+
+	var listingID uuid.UUID
+	err := tx.QueryRow(ctx, q, order.ItemID, order.Currency, order.Price, order.BuyerID).Scan(&listingID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return r.fill(ctx, tx, order.ID, listingID, order.BuyerID)
+}
+
+// matchOrder fills the best active buy order for a new listing: the highest
+// price at or above the listing's, oldest first. Orders locked by a
+// concurrent match are skipped, so two listings never fill the same order.
+func (r *RepositoryBuyOrder) matchOrder(ctx context.Context, tx pgx.Tx, listingID, itemID uuid.UUID) error {
+	q := `
+		SELECT
+			o.id,
+			o.buyer_id
+		FROM public.buy_order o
+		JOIN public.listing l ON l.id = $1
+		WHERE
+			o.item_id = l.item_id
+		AND
+			o.currency = l.currency
+		AND
+			o.price >= l.price
+		AND
+			o.buyer_id <> l.seller_id
+		AND
+			o.status = 'active'
+		ORDER BY o.price DESC, o.created_at, o.id
+		LIMIT 1
+		FOR UPDATE OF o SKIP LOCKED
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	if err := lockOrderBook(ctx, tx, itemID); err != nil {
+		return err
+	}
+
+	var orderID, buyerID uuid.UUID
+	err := tx.QueryRow(ctx, q, listingID).Scan(&orderID, &buyerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return r.fill(ctx, tx, orderID, listingID, buyerID)
+}
+
+// lockOrderBook serializes matching on one item until the end of the
+// transaction. Without it a buy order and a listing inserted concurrently
+// would not see each other and both stay open although their prices cross.
+func lockOrderBook(ctx context.Context, tx pgx.Tx, itemID uuid.UUID) error {
+	_, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('order_book:' || $1::text))`, itemID)
+	return err
+}
+
+// fill sells the listing to the buyer of the order at the listing price and
+// marks the order as filled. The seller is paid from the funds held by the
+// order; what is left over goes back to the buyer's wallet.
+func (r *RepositoryBuyOrder) fill(ctx context.Context, tx pgx.Tx, orderID, listingID, buyerID uuid.UUID) error {
+	listings := &RepositoryListing{client: r.client, logger: r.logger}
+	listing, err := listings.closeListing(ctx, tx, listingID, "sold", &buyerID)
+	if err != nil {
+		return err
+	}
+
+	if err := sellListingItem(ctx, tx, listingID, buyerID); err != nil {
+		return err
+	}
+
+	q := `
+		UPDATE public.buy_order
+		SET
+			status = 'filled',
+			listing_id = $2,
+			fill_price = $3,
+			closed_at = CURRENT_TIMESTAMP
+		WHERE
+			id = $1
+		AND
+			status = 'active'
+		RETURNING price
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var held float64
+	err = tx.QueryRow(ctx, q, orderID, listingID, listing.Price).Scan(&held)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrBuyOrderNotActive
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = postLedger(ctx, tx, ledgerTransfer{
+		Kind:        LedgerBuyOrderFill,
+		Currency:    listing.Currency,
+		ReferenceID: &orderID,
+		CreatedBy:   &buyerID,
+		Postings: []ledgerPosting{
+			{UserID: buyerID, Account: AccountEscrow, Amount: -held},
+			{UserID: buyerID, Account: AccountWallet, Amount: held - listing.Price},
+			{UserID: listing.SellerID, Account: AccountWallet, Amount: listing.Price},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	r.logger.Infof("Filled buy order %s from listing %s", orderID, listingID)
+	return nil
+}
+
+func scanBuyOrder(row pgx.Row) (BuyOrderData, error) {
+	var o BuyOrderData
+	err := row.Scan(&o.ID, &o.BuyerID, &o.ItemID, &o.Name, &o.Rarity, &o.Price, &o.Currency, &o.Status, &o.ListingID, &o.FillPrice, &o.CreatedAt, &o.ClosedAt)
+	return o, err
+}
@@ -0,0 +1,411 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+var (
+	// ErrItemNotOwned is returned when a user has no inventory instance of an
+	// item that has to be reserved or moved out of their inventory.
+	ErrItemNotOwned = errors.New("item is not in user's inventory")
+	// ErrItemReserved is returned when every instance of an item the user owns
+	// is already held in escrow by another pending trade.
+	ErrItemReserved = errors.New("item is reserved by another pending trade")
+)
+
+type RepositoryInventory struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+type InventoryItemData struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	ItemID     uuid.UUID  `json:"item_id"`
+	Name       string     `json:"name"`
+	Rarity     string     `json:"rarity"`
+	Quality    string     `json:"quality,omitempty"`
+	TradeID    *uuid.UUID `json:"trade_id,omitempty"`
+	ListingID  *uuid.UUID `json:"listing_id,omitempty"`
+	AuctionID  *uuid.UUID `json:"auction_id,omitempty"`
+	AcquiredAt time.Time  `json:"acquired_at"`
+}
+
+func NewRepositoryInventory(logger *logging.Logger) *RepositoryInventory {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryInventory{
+		client: client,
+		logger: logger,
+	}
+}
+
+func (r *RepositoryInventory) Create(ctx context.Context, i interface{}) (interface{}, error) {
+	q := `
+		INSERT INTO public.inventory (
+			id,
+			user_id,
+			item_id,
+			acquired_at
+		)
+		VALUES (
+			gen_random_uuid(),
+			$1,
+			$2,
+			CURRENT_TIMESTAMP
+		)
+		RETURNING id
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+	data := i.(InventoryItemData)
+
+	if err := r.client.QueryRow(ctx, q, data.UserID, data.ItemID).Scan(&data.ID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			newErr := fmt.Errorf("SQL Error: %s, Detail: %s, Where: %s, Code: %s, SQLState: %s", pgErr.Message, pgErr.Detail, pgErr.Where, pgErr.Code, pgErr.SQLState())
+			r.logger.Error(newErr)
+			return nil, newErr
+		}
+		return nil, err
+	}
+
+	r.logger.Infof("Completed to create inventory item: %v", data)
+	return data.ID, nil
+}
+
+func (r *RepositoryInventory) FindByUserID(ctx context.Context, userID string) ([]InventoryItemData, error) {
+	q := `
+		SELECT
+			inv.id,
+			inv.user_id,
+			inv.item_id,
+			i.name,
+			i.rarity,
+			i.quality,
+			inv.trade_id,
+			inv.listing_id,
+			inv.auction_id,
+			inv.acquired_at
+		FROM public.inventory inv
+		JOIN public.item i ON i.id = inv.item_id
+		WHERE
+			inv.user_id = $1
+		ORDER BY inv.acquired_at, inv.id
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]InventoryItemData, 0)
+	for rows.Next() {
+		var it InventoryItemData
+
+		if err := rows.Scan(&it.ID, &it.UserID, &it.ItemID, &it.Name, &it.Rarity, &it.Quality, &it.TradeID, &it.ListingID, &it.AuctionID, &it.AcquiredAt); err != nil {
+			return nil, err
+		}
+
+		items = append(items, it)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// transferInventoryItem moves the oldest free instance of itemID from one
+// user's inventory to another's within tx. Instances held in escrow by a
+// pending trade, an active listing or an auction are never picked.
+func transferInventoryItem(ctx context.Context, tx pgx.Tx, itemID, from, to uuid.UUID) error {
+	q := `
+		UPDATE public.inventory
+		SET
+			user_id = $3,
+			acquired_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT
+				id
+			FROM public.inventory
+			WHERE
+				user_id = $1
+			AND
+				item_id = $2
+			AND
+				trade_id IS NULL
+			AND
+				listing_id IS NULL
+			AND
+				auction_id IS NULL
+			ORDER BY acquired_at, id
+			LIMIT 1
+			FOR UPDATE
+		)
+	`
+
+	tag, err := tx.Exec(ctx, q, from, itemID, to)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return unavailableItemError(ctx, tx, itemID, from)
+	}
+
+	return nil
+}
+
+// reserveInventoryItem puts one free instance of itemID owned by userID in
+// escrow for the given trade.
+func reserveInventoryItem(ctx context.Context, tx pgx.Tx, tradeID, userID, itemID uuid.UUID) error {
+	q := `
+		UPDATE public.inventory
+		SET
+			trade_id = $1
+		WHERE id = (
+			SELECT
+				id
+			FROM public.inventory
+			WHERE
+				user_id = $2
+			AND
+				item_id = $3
+			AND
+				trade_id IS NULL
+			AND
+				listing_id IS NULL
+			AND
+				auction_id IS NULL
+			ORDER BY acquired_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+
+	tag, err := tx.Exec(ctx, q, tradeID, userID, itemID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return unavailableItemError(ctx, tx, itemID, userID)
+	}
+
+	return nil
+}
+
+// reserveListingItem puts one free instance of itemID owned by userID in
+// escrow for the given listing.
+func reserveListingItem(ctx context.Context, tx pgx.Tx, listingID, userID, itemID uuid.UUID) error {
+	q := `
+		UPDATE public.inventory
+		SET
+			listing_id = $1
+		WHERE id = (
+			SELECT
+				id
+			FROM public.inventory
+			WHERE
+				user_id = $2
+			AND
+				item_id = $3
+			AND
+				trade_id IS NULL
+			AND
+				listing_id IS NULL
+			AND
+				auction_id IS NULL
+			ORDER BY acquired_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+
+	tag, err := tx.Exec(ctx, q, listingID, userID, itemID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return unavailableItemError(ctx, tx, itemID, userID)
+	}
+
+	return nil
+}
+
+// sellListingItem hands the instance held in escrow by the listing over to
+// the buyer and releases the reservation.
+func sellListingItem(ctx context.Context, tx pgx.Tx, listingID, buyerID uuid.UUID) error {
+	q := `
+		UPDATE public.inventory
+		SET
+			user_id = $2,
+			listing_id = NULL,
+			acquired_at = CURRENT_TIMESTAMP
+		WHERE
+			listing_id = $1
+	`
+
+	tag, err := tx.Exec(ctx, q, listingID, buyerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return fmt.Errorf("%w: listing %s holds %d items in escrow", ErrItemNotOwned, listingID, tag.RowsAffected())
+	}
+
+	return nil
+}
+
+// releaseListingItem returns the instance held in escrow by the listing.
+func releaseListingItem(ctx context.Context, tx pgx.Tx, listingID uuid.UUID) error {
+	q := `
+		UPDATE public.inventory
+		SET
+			listing_id = NULL
+		WHERE
+			listing_id = $1
+	`
+
+	if _, err := tx.Exec(ctx, q, listingID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// reserveAuctionItem puts one free instance of itemID owned by userID in
+// escrow for the given auction.
+func reserveAuctionItem(ctx context.Context, tx pgx.Tx, auctionID, userID, itemID uuid.UUID) error {
+	q := `
+		UPDATE public.inventory
+		SET
+			auction_id = $1
+		WHERE id = (
+			SELECT
+				id
+			FROM public.inventory
+			WHERE
+				user_id = $2
+			AND
+				item_id = $3
+			AND
+				trade_id IS NULL
+			AND
+				listing_id IS NULL
+			AND
+				auction_id IS NULL
+			ORDER BY acquired_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+	`
+
+	tag, err := tx.Exec(ctx, q, auctionID, userID, itemID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return unavailableItemError(ctx, tx, itemID, userID)
+	}
+
+	return nil
+}
+
+// releaseAuctionItem returns the instance held in escrow by the auction.
+func releaseAuctionItem(ctx context.Context, tx pgx.Tx, auctionID uuid.UUID) error {
+	q := `
+		UPDATE public.inventory
+		SET
+			auction_id = NULL
+		WHERE
+			auction_id = $1
+	`
+
+	if _, err := tx.Exec(ctx, q, auctionID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// releaseInventoryItems returns every instance held in escrow for the trade.
+func releaseInventoryItems(ctx context.Context, tx pgx.Tx, tradeID string) error {
+	q := `
+		UPDATE public.inventory
+		SET
+			trade_id = NULL
+		WHERE
+			trade_id = $1
+	`
+
+	if _, err := tx.Exec(ctx, q, tradeID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// transferReservedItems hands every instance held in escrow for the trade
+// over to the given user and releases the reservation.
+func transferReservedItems(ctx context.Context, tx pgx.Tx, tradeID string, to uuid.UUID) (int64, error) {
+	q := `
+		UPDATE public.inventory
+		SET
+			user_id = $2,
+			trade_id = NULL,
+			acquired_at = CURRENT_TIMESTAMP
+		WHERE
+			trade_id = $1
+	`
+
+	tag, err := tx.Exec(ctx, q, tradeID, to)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// unavailableItemError tells apart an item the user does not own at all from
+// one whose instances are all held in escrow.
+func unavailableItemError(ctx context.Context, tx pgx.Tx, itemID, userID uuid.UUID) error {
+	q := `
+		SELECT EXISTS (
+			SELECT
+				1
+			FROM public.inventory
+			WHERE
+				user_id = $1
+			AND
+				item_id = $2
+		)
+	`
+
+	var owned bool
+	if err := tx.QueryRow(ctx, q, userID, itemID).Scan(&owned); err != nil {
+		return err
+	}
+	if owned {
+		return fmt.Errorf("%w: item %s, user %s", ErrItemReserved, itemID, userID)
+	}
+
+	return fmt.Errorf("%w: item %s, user %s", ErrItemNotOwned, itemID, userID)
+}
@@ -15,6 +15,11 @@ import (
 
 )
 
+// ItemRarities lists the Dota 2 item rarities from the most common to the
+// rarest, in lower case. The list lives in config so rarities named in
+// config.yaml can be checked at startup.
+var ItemRarities = config.ItemRarities
+
 type RepositoryItem struct {
 	client postgresql.Client
 	logger *logging.Logger
@@ -144,6 +149,42 @@ func (r *RepositoryItem) FindOne(ctx context.Context, id string) (ItemData, erro
 	return it, nil
 }
 
+func (r *RepositoryItem) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]ItemData, error) {
+	q := `
+        SELECT 
+			id, 
+			name, 
+			rarity, 
+			quality 
+		FROM public.item 
+		WHERE id = ANY($1)
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+	rows, err := r.client.Query(ctx, q, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]ItemData, 0, len(ids))
+
+	for rows.Next() {
+		var it ItemData
+
+		if err := rows.Scan(&it.ItemId, &it.Name, &it.Rarity, &it.Quality); err != nil {
+			return nil, err
+		}
+
+		items = append(items, it)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
 func (r *RepositoryItem) Update(ctx context.Context, item interface{}) (interface{}, error) {
 	q := `
 		UPDATE public.item
@@ -0,0 +1,202 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+type RepositoryItemPrice struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+// PriceStats are the market price statistics of an item over one period.
+type PriceStats struct {
+	Average float64 `json:"average"`
+	Median  float64 `json:"median"`
+	Lowest  float64 `json:"lowest"`
+	Highest float64 `json:"highest"`
+}
+
+// ItemPriceData is the market price of an item, keyed by the item name.
+type ItemPriceData struct {
+	Name           string     `json:"name"`
+	Currency       string     `json:"currency"`
+	Hours24        PriceStats `json:"24_hours"`
+	Days7          PriceStats `json:"7_days"`
+	Days30         PriceStats `json:"30_days"`
+	AllTime        PriceStats `json:"all_time"`
+	OPSKins        float64    `json:"opskins_average"`
+	ReferencePrice *float64   `json:"reference_price,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func NewRepositoryItemPrice(logger *logging.Logger) *RepositoryItemPrice {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryItemPrice{
+		client: client,
+		logger: logger,
+	}
+}
+
+// UpsertMany stores the given prices in one transaction, replacing the
+// previous prices of the same items. A snapshot of every price is kept for
+// the price history.
+func (r *RepositoryItemPrice) UpsertMany(ctx context.Context, prices []ItemPriceData) (err error) {
+	q := `
+		INSERT INTO public.item_price (
+			name,
+			currency,
+			average_24h,
+			median_24h,
+			lowest_24h,
+			highest_24h,
+			average_7d,
+			median_7d,
+			lowest_7d,
+			highest_7d,
+			average_30d,
+			median_30d,
+			lowest_30d,
+			highest_30d,
+			average_all_time,
+			median_all_time,
+			lowest_all_time,
+			highest_all_time,
+			opskins_average,
+			reference_price,
+			updated_at)
+		VALUES (
+			$1, $2,
+			$3, $4, $5, $6,
+			$7, $8, $9, $10,
+			$11, $12, $13, $14,
+			$15, $16, $17, $18,
+			$19, $20,
+			CURRENT_TIMESTAMP)
+		ON CONFLICT (name) DO UPDATE SET
+			currency = EXCLUDED.currency,
+			average_24h = EXCLUDED.average_24h,
+			median_24h = EXCLUDED.median_24h,
+			lowest_24h = EXCLUDED.lowest_24h,
+			highest_24h = EXCLUDED.highest_24h,
+			average_7d = EXCLUDED.average_7d,
+			median_7d = EXCLUDED.median_7d,
+			lowest_7d = EXCLUDED.lowest_7d,
+			highest_7d = EXCLUDED.highest_7d,
+			average_30d = EXCLUDED.average_30d,
+			median_30d = EXCLUDED.median_30d,
+			lowest_30d = EXCLUDED.lowest_30d,
+			highest_30d = EXCLUDED.highest_30d,
+			average_all_time = EXCLUDED.average_all_time,
+			median_all_time = EXCLUDED.median_all_time,
+			lowest_all_time = EXCLUDED.lowest_all_time,
+			highest_all_time = EXCLUDED.highest_all_time,
+			opskins_average = EXCLUDED.opskins_average,
+			reference_price = EXCLUDED.reference_price,
+			updated_at = EXCLUDED.updated_at
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	snapshot := `
+		INSERT INTO public.item_price_snapshot (
+			name,
+			currency,
+			average_24h,
+			lowest_24h,
+			highest_24h,
+			reference_price,
+			captured_at)
+		VALUES (
+			$1, $2,
+			$3, $4, $5,
+			$6,
+			CURRENT_TIMESTAMP)
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(snapshot)))
+
+	batch := &pgx.Batch{}
+	for _, p := range prices {
+		batch.Queue(q,
+			p.Name, p.Currency,
+			p.Hours24.Average, p.Hours24.Median, p.Hours24.Lowest, p.Hours24.Highest,
+			p.Days7.Average, p.Days7.Median, p.Days7.Lowest, p.Days7.Highest,
+			p.Days30.Average, p.Days30.Median, p.Days30.Lowest, p.Days30.Highest,
+			p.AllTime.Average, p.AllTime.Median, p.AllTime.Lowest, p.AllTime.Highest,
+			p.OPSKins, p.ReferencePrice,
+		)
+		batch.Queue(snapshot,
+			p.Name, p.Currency,
+			p.Hours24.Average, p.Hours24.Lowest, p.Hours24.Highest,
+			p.ReferencePrice,
+		)
+	}
+
+	err = tx.SendBatch(ctx, batch).Close()
+	return err
+}
+
+// FindReferencePrices returns the reference price of every given item that
+// has one, keyed by item ID.
+func (r *RepositoryItemPrice) FindReferencePrices(ctx context.Context, itemIDs []uuid.UUID) (map[uuid.UUID]float64, error) {
+	q := `
+		SELECT
+			i.id,
+			p.reference_price
+		FROM public.item i
+		JOIN public.item_price p ON p.name = i.name
+		WHERE
+			i.id = ANY($1)
+		AND
+			p.reference_price IS NOT NULL
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[uuid.UUID]float64, len(itemIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		var price float64
+		if err := rows.Scan(&id, &price); err != nil {
+			return nil, err
+		}
+		prices[id] = price
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
@@ -0,0 +1,333 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+var (
+	ErrListingNotFound = errors.New("listing not found")
+	// ErrListingNotActive is returned when a listing was already sold or
+	// withdrawn, usually by a concurrent request.
+	ErrListingNotActive = errors.New("listing is not active")
+	// ErrInvalidPrice is returned when a price rounds to less than a cent
+	// and breaks the CHECK constraint of its column.
+	ErrInvalidPrice = errors.New("price must be at least 0.01")
+)
+
+// priceViolation turns a CHECK violation of a price column into
+// ErrInvalidPrice, or returns err unchanged.
+func priceViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23514" {
+		return fmt.Errorf("%w: %s", ErrInvalidPrice, pgErr.ConstraintName)
+	}
+	return err
+}
+
+// listingSelect is the projection shared by all queries returning listings.
+// Rows must be read with scanListing.
+const listingSelect = `
+		SELECT
+			l.id,
+			l.seller_id,
+			l.item_id,
+			i.name,
+			i.rarity,
+			i.quality,
+			l.price,
+			l.currency,
+			l.status,
+			l.buyer_id,
+			l.created_at,
+			l.closed_at
+		FROM public.listing l
+		JOIN public.item i ON i.id = l.item_id
+`
+
+type RepositoryListing struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+type ListingData struct {
+	ID        uuid.UUID  `json:"id"`
+	SellerID  uuid.UUID  `json:"seller_id"`
+	ItemID    uuid.UUID  `json:"item_id"`
+	Name      string     `json:"name"`
+	Rarity    string     `json:"rarity"`
+	Quality   string     `json:"quality,omitempty"`
+	Price     float64    `json:"price"`
+	Currency  string     `json:"currency"`
+	Status    string     `json:"status"`
+	BuyerID   *uuid.UUID `json:"buyer_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty"`
+}
+
+// ListingFilter narrows the listings returned by FindAll. Zero values disable
+// the corresponding condition.
+type ListingFilter struct {
+	Status   string
+	SellerID uuid.UUID
+	ItemID   uuid.UUID
+}
+
+func NewRepositoryListing(logger *logging.Logger) *RepositoryListing {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryListing{
+		client: client,
+		logger: logger,
+	}
+}
+
+// Create stores an active listing and puts one free instance of the item
+// from the seller's inventory in escrow. The listing is sold right away when
+// a buy order at or above its price is waiting. An empty currency falls back
+// to the currency of the item's market price, then to defaultCurrency.
+func (r *RepositoryListing) Create(ctx context.Context, data ListingData, defaultCurrency string) (_ uuid.UUID, err error) {
+	q := `
+		INSERT INTO public.listing (
+			id,
+			seller_id,
+			item_id,
+			price,
+			currency,
+			status,
+			created_at
+		)
+		VALUES (
+			gen_random_uuid(),
+			$1,
+			$2,
+			$3,
+			COALESCE(
+				NULLIF($4, ''),
+				(SELECT p.currency FROM public.item_price p JOIN public.item i ON i.name = p.name WHERE i.id = $2),
+				$5
+			),
+			'active',
+			CURRENT_TIMESTAMP
+		)
+		RETURNING id
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	if err = tx.QueryRow(ctx, q, data.SellerID, data.ItemID, data.Price, data.Currency, defaultCurrency).Scan(&data.ID); err != nil {
+		return uuid.Nil, priceViolation(err)
+	}
+
+	if err = reserveListingItem(ctx, tx, data.ID, data.SellerID, data.ItemID); err != nil {
+		r.logger.Infof("Failed to reserve listed item: %v", err)
+		return uuid.Nil, err
+	}
+
+	orders := &RepositoryBuyOrder{client: r.client, logger: r.logger}
+	if err = orders.matchOrder(ctx, tx, data.ID, data.ItemID); err != nil {
+		r.logger.Infof("Failed to match listing: %v", err)
+		return uuid.Nil, err
+	}
+
+	r.logger.Infof("Completed to create listing: %v", data)
+	return data.ID, nil
+}
+
+func (r *RepositoryListing) FindOne(ctx context.Context, listingID string) (ListingData, error) {
+	q := listingSelect + `
+		WHERE
+			l.id = $1
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	l, err := scanListing(r.client.QueryRow(ctx, q, listingID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ListingData{}, ErrListingNotFound
+	}
+	if err != nil {
+		return ListingData{}, err
+	}
+
+	return l, nil
+}
+
+// FindAll returns the listings matching the filter, newest first.
+func (r *RepositoryListing) FindAll(ctx context.Context, filter ListingFilter) ([]ListingData, error) {
+	var conds []string
+	var args []any
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conds = append(conds, fmt.Sprintf("l.status = $%d", len(args)))
+	}
+	if filter.SellerID != uuid.Nil {
+		args = append(args, filter.SellerID)
+		conds = append(conds, fmt.Sprintf("l.seller_id = $%d", len(args)))
+	}
+	if filter.ItemID != uuid.Nil {
+		args = append(args, filter.ItemID)
+		conds = append(conds, fmt.Sprintf("l.item_id = $%d", len(args)))
+	}
+
+	cond := "TRUE"
+	if len(conds) > 0 {
+		cond = strings.Join(conds, " AND ")
+	}
+
+	q := listingSelect + fmt.Sprintf(`
+		WHERE
+			%s
+		ORDER BY l.created_at DESC, l.id DESC
+	`, cond)
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	listings := make([]ListingData, 0)
+	for rows.Next() {
+		l, err := scanListing(rows)
+		if err != nil {
+			return nil, err
+		}
+		listings = append(listings, l)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return listings, nil
+}
+
+// Buy marks an active listing as sold to buyerID, pays the seller from the
+// buyer's wallet and hands the item held in escrow over to the buyer.
+func (r *RepositoryListing) Buy(ctx context.Context, listingID, buyerID uuid.UUID) (err error) {
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	listing, err := r.closeListing(ctx, tx, listingID, "sold", &buyerID)
+	if err != nil {
+		return err
+	}
+
+	if err = sellListingItem(ctx, tx, listingID, buyerID); err != nil {
+		return err
+	}
+
+	_, err = postLedger(ctx, tx, ledgerTransfer{
+		Kind:        LedgerListingSale,
+		Currency:    listing.Currency,
+		ReferenceID: &listingID,
+		CreatedBy:   &buyerID,
+		Postings: []ledgerPosting{
+			{UserID: buyerID, Account: AccountWallet, Amount: -listing.Price},
+			{UserID: listing.SellerID, Account: AccountWallet, Amount: listing.Price},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Withdraw takes an active listing off the market and returns the item held
+// in escrow to the seller.
+func (r *RepositoryListing) Withdraw(ctx context.Context, listingID uuid.UUID) (err error) {
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	if _, err = r.closeListing(ctx, tx, listingID, "withdrawn", nil); err != nil {
+		return err
+	}
+
+	if err = releaseListingItem(ctx, tx, listingID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// closeListing moves an active listing to the given final status and returns
+// its seller, price and currency.
+func (r *RepositoryListing) closeListing(ctx context.Context, tx pgx.Tx, listingID uuid.UUID, status string, buyerID *uuid.UUID) (ListingData, error) {
+	q := `
+		UPDATE public.listing
+		SET
+			status = $2,
+			buyer_id = $3,
+			closed_at = CURRENT_TIMESTAMP
+		WHERE
+			id = $1
+		AND
+			status = 'active'
+		RETURNING seller_id, price, currency
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	l := ListingData{ID: listingID}
+	err := tx.QueryRow(ctx, q, listingID, status, buyerID).Scan(&l.SellerID, &l.Price, &l.Currency)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ListingData{}, ErrListingNotActive
+	}
+	if err != nil {
+		return ListingData{}, err
+	}
+
+	return l, nil
+}
+
+func scanListing(row pgx.Row) (ListingData, error) {
+	var l ListingData
+	err := row.Scan(&l.ID, &l.SellerID, &l.ItemID, &l.Name, &l.Rarity, &l.Quality, &l.Price, &l.Currency, &l.Status, &l.BuyerID, &l.CreatedAt, &l.ClosedAt)
+	return l, err
+}
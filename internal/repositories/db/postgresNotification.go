@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+type RepositoryNotification struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+type NotificationData struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Kind      string     `json:"kind"`
+	TradeID   *uuid.UUID `json:"trade_id,omitempty"`
+	ItemID    *uuid.UUID `json:"item_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+}
+
+func NewRepositoryNotification(logger *logging.Logger) *RepositoryNotification {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryNotification{
+		client: client,
+		logger: logger,
+	}
+}
+
+// FindByUserID returns up to limit notifications of the user, newest first.
+func (r *RepositoryNotification) FindByUserID(ctx context.Context, userID string, unreadOnly bool, limit int) ([]NotificationData, error) {
+	q := `
+		SELECT
+			id,
+			user_id,
+			kind,
+			trade_id,
+			item_id,
+			created_at,
+			read_at
+		FROM public.notification
+		WHERE
+			user_id = $1
+		AND
+			(NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, userID, unreadOnly, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := make([]NotificationData, 0)
+	for rows.Next() {
+		var n NotificationData
+
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Kind, &n.TradeID, &n.ItemID, &n.CreatedAt, &n.ReadAt); err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+func (r *RepositoryNotification) MarkRead(ctx context.Context, userID, notificationID string) error {
+	q := `
+		UPDATE public.notification
+		SET
+			read_at = COALESCE(read_at, CURRENT_TIMESTAMP)
+		WHERE
+			id = $1
+		AND
+			user_id = $2
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tag, err := r.client.Exec(ctx, q, notificationID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+// Price history buckets, see migrations/020_create_item_price_history_table.sql.
+const (
+	PriceBucketHour = "hour"
+	PriceBucketDay  = "day"
+	PriceBucketWeek = "week"
+)
+
+var PriceBuckets = []string{PriceBucketHour, PriceBucketDay, PriceBucketWeek}
+
+type RepositoryPriceHistory struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+// PricePointData is the price of an item in one currency over one bucket.
+// Samples come from catalog imports and from completed trades.
+type PricePointData struct {
+	BucketStart   time.Time `json:"bucket_start"`
+	Currency      string    `json:"currency"`
+	Average       float64   `json:"average"`
+	Lowest        float64   `json:"lowest"`
+	Highest       float64   `json:"highest"`
+	Samples       int       `json:"samples"`
+	ImportSamples int       `json:"import_samples"`
+	TradeSamples  int       `json:"trade_samples"`
+}
+
+func NewRepositoryPriceHistory(logger *logging.Logger) *RepositoryPriceHistory {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryPriceHistory{
+		client: client,
+		logger: logger,
+	}
+}
+
+// Rollup recomputes the buckets of the given size that start at or after the
+// bucket containing since, and returns how many buckets were written. When
+// the bucket size has no history yet, everything is rolled up. Buckets are
+// cut at UTC boundaries whatever the time zone of the session.
+//
+// An import sample is the 24 hours average of a snapshot (or its reference
+// price). A trade sample is the implied unit price of an item: a side of a
+// completed trade holding a single kind of item is worth the other side,
+// valued at the reference prices known when the trade completed. Trades
+// with unpriced items or mixed currencies on the other side are skipped.
+func (r *RepositoryPriceHistory) Rollup(ctx context.Context, bucket string, since time.Time) (int64, error) {
+	var from *time.Time
+	var exists bool
+	if err := r.client.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM public.item_price_history WHERE bucket = $1)`, bucket).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if exists {
+		from = &since
+	}
+
+	q := `
+		WITH completed AS (
+			SELECT
+				trade_id,
+				max(created_at) AS completed_at
+			FROM public.trade_event
+			WHERE
+				event_type = 'status_changed'
+			AND
+				payload->>'to' = 'completed'
+			GROUP BY trade_id
+			HAVING
+				$2::timestamptz IS NULL
+			OR
+				max(created_at) >= date_trunc($1::text, $2::timestamptz, 'UTC')
+		),
+		lines AS (
+			SELECT
+				c.trade_id,
+				c.completed_at,
+				ti.item_id,
+				ti.item_status,
+				ti.quantity,
+				CASE WHEN s.currency IS NOT NULL THEN s.reference_price ELSE p.reference_price END AS price,
+				COALESCE(s.currency, p.currency) AS currency
+			FROM completed c
+			JOIN public.trade_item ti ON ti.trade_id = c.trade_id
+			JOIN public.item i ON i.id = ti.item_id
+			LEFT JOIN public.item_price p ON p.name = i.name
+			LEFT JOIN LATERAL (
+				SELECT
+					reference_price,
+					currency
+				FROM public.item_price_snapshot
+				WHERE
+					name = i.name
+				AND
+					captured_at <= c.completed_at
+				ORDER BY captured_at DESC
+				LIMIT 1
+			) s ON true
+		),
+		sides AS (
+			SELECT
+				trade_id,
+				completed_at,
+				item_status,
+				count(DISTINCT item_id) AS kinds,
+				min(item_id::text)::uuid AS item_id,
+				sum(quantity) AS quantity,
+				CASE WHEN count(*) FILTER (WHERE price IS NULL) = 0 THEN sum(quantity * price) END AS value,
+				count(DISTINCT currency) AS currencies,
+				min(currency) AS currency
+			FROM lines
+			GROUP BY trade_id, completed_at, item_status
+		),
+		samples AS (
+			SELECT
+				i.id AS item_id,
+				s.captured_at AS sampled_at,
+				s.currency,
+				COALESCE(NULLIF(s.average_24h, 0), s.reference_price) AS price,
+				true AS imported
+			FROM public.item_price_snapshot s
+			JOIN public.item i ON i.name = s.name
+			WHERE
+				COALESCE(NULLIF(s.average_24h, 0), s.reference_price) > 0
+			AND
+				($2::timestamptz IS NULL OR s.captured_at >= date_trunc($1::text, $2::timestamptz, 'UTC'))
+			UNION ALL
+			SELECT
+				s.item_id,
+				s.completed_at,
+				o.currency,
+				o.value / s.quantity,
+				false
+			FROM sides s
+			JOIN sides o ON o.trade_id = s.trade_id AND o.item_status <> s.item_status
+			WHERE
+				s.kinds = 1
+			AND
+				o.value > 0
+			AND
+				o.currencies = 1
+		)
+		INSERT INTO public.item_price_history (
+			item_id,
+			bucket,
+			bucket_start,
+			currency,
+			average,
+			lowest,
+			highest,
+			samples,
+			import_samples,
+			trade_samples,
+			updated_at
+		)
+		SELECT
+			item_id,
+			$1::text,
+			date_trunc($1::text, sampled_at, 'UTC'),
+			currency,
+			avg(price),
+			min(price),
+			max(price),
+			count(*),
+			count(*) FILTER (WHERE imported),
+			count(*) FILTER (WHERE NOT imported),
+			CURRENT_TIMESTAMP
+		FROM samples
+		GROUP BY item_id, date_trunc($1::text, sampled_at, 'UTC'), currency
+		ON CONFLICT (item_id, bucket, bucket_start, currency) DO UPDATE SET
+			average = EXCLUDED.average,
+			lowest = EXCLUDED.lowest,
+			highest = EXCLUDED.highest,
+			samples = EXCLUDED.samples,
+			import_samples = EXCLUDED.import_samples,
+			trade_samples = EXCLUDED.trade_samples,
+			updated_at = EXCLUDED.updated_at
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tag, err := r.client.Exec(ctx, q, bucket, from)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// FindHistory returns the buckets of an item between from and to, oldest
+// first. An empty currency matches every currency.
+func (r *RepositoryPriceHistory) FindHistory(ctx context.Context, itemID uuid.UUID, bucket string, from, to time.Time, currency string) ([]PricePointData, error) {
+	var exists bool
+	if err := r.client.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM public.item WHERE id = $1)`, itemID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrItemNotFound, itemID)
+	}
+
+	q := `
+		SELECT
+			bucket_start,
+			currency,
+			average,
+			lowest,
+			highest,
+			samples,
+			import_samples,
+			trade_samples
+		FROM public.item_price_history
+		WHERE
+			item_id = $1
+		AND
+			bucket = $2::text
+		AND
+			bucket_start >= date_trunc($2::text, $3::timestamptz, 'UTC')
+		AND
+			bucket_start <= $4::timestamptz
+		AND
+			($5::text = '' OR currency = $5::text)
+		ORDER BY bucket_start, currency
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, itemID, bucket, from, to, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := pgx.CollectRows(rows, pgx.RowToStructByPos[PricePointData])
+	if err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
@@ -0,0 +1,211 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+var (
+	ErrTradeNotCompleted = errors.New("trade is not completed")
+	ErrNotTradeParty     = errors.New("user is not a party of the trade")
+	ErrTradeAlreadyRated = errors.New("trade is already rated by this user")
+)
+
+type RepositoryRating struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+// TradeRatingData is the score one party of a completed trade gave the
+// other one.
+type TradeRatingData struct {
+	ID        uuid.UUID `json:"rating_id"`
+	TradeID   uuid.UUID `json:"trade_id"`
+	RaterID   uuid.UUID `json:"rater_id"`
+	RateeID   uuid.UUID `json:"ratee_id"`
+	Score     int       `json:"score"`
+	Comment   *string   `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ReputationData sums up the ratings a user received. Average is nil until
+// the user is rated.
+type ReputationData struct {
+	Average         *float64 `json:"average"`
+	Count           int      `json:"count"`
+	CompletedTrades int      `json:"completed_trades"`
+}
+
+func NewRepositoryRating(logger *logging.Logger) *RepositoryRating {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryRating{
+		client: client,
+		logger: logger,
+	}
+}
+
+// Create stores the rating raterID gives the other party of a completed
+// trade. Each party rates a trade at most once.
+func (r *RepositoryRating) Create(ctx context.Context, tradeID, raterID uuid.UUID, score int, comment *string) (TradeRatingData, error) {
+	q := `
+		SELECT
+			user_id,
+			accepted_by,
+			status
+		FROM public.trade
+		WHERE
+			id = $1
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var ownerID uuid.UUID
+	var counterpartyID *uuid.UUID
+	var status string
+	if err := r.client.QueryRow(ctx, q, tradeID).Scan(&ownerID, &counterpartyID, &status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TradeRatingData{}, fmt.Errorf("%w: %s", ErrTradeNotFound, tradeID)
+		}
+		return TradeRatingData{}, err
+	}
+
+	if status != "completed" || counterpartyID == nil {
+		return TradeRatingData{}, fmt.Errorf("%w: %s", ErrTradeNotCompleted, status)
+	}
+
+	var rateeID uuid.UUID
+	switch raterID {
+	case ownerID:
+		rateeID = *counterpartyID
+	case *counterpartyID:
+		rateeID = ownerID
+	default:
+		return TradeRatingData{}, fmt.Errorf("%w: %s", ErrNotTradeParty, tradeID)
+	}
+
+	q = `
+		INSERT INTO public.trade_rating (
+			id,
+			trade_id,
+			rater_id,
+			ratee_id,
+			score,
+			comment,
+			created_at
+		)
+		VALUES (
+			gen_random_uuid(),
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			CURRENT_TIMESTAMP
+		)
+		ON CONFLICT (trade_id, rater_id) DO NOTHING
+		RETURNING id, created_at
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rating := TradeRatingData{
+		TradeID: tradeID,
+		RaterID: raterID,
+		RateeID: rateeID,
+		Score:   score,
+		Comment: comment,
+	}
+	err := r.client.QueryRow(ctx, q, tradeID, raterID, rateeID, score, comment).Scan(&rating.ID, &rating.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return TradeRatingData{}, fmt.Errorf("%w: %s", ErrTradeAlreadyRated, tradeID)
+	}
+	if err != nil {
+		return TradeRatingData{}, err
+	}
+
+	r.logger.Infof("Completed to rate trade %s: %s -> %s", tradeID, raterID, rateeID)
+	return rating, nil
+}
+
+// FindByRatee returns the latest limit ratings the user received, newest
+// first.
+func (r *RepositoryRating) FindByRatee(ctx context.Context, userID uuid.UUID, limit int) ([]TradeRatingData, error) {
+	q := `
+		SELECT
+			id,
+			trade_id,
+			rater_id,
+			ratee_id,
+			score,
+			comment,
+			created_at
+		FROM public.trade_rating
+		WHERE
+			ratee_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := make([]TradeRatingData, 0)
+	for rows.Next() {
+		var rating TradeRatingData
+
+		if err := rows.Scan(&rating.ID, &rating.TradeID, &rating.RaterID, &rating.RateeID, &rating.Score, &rating.Comment, &rating.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		ratings = append(ratings, rating)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ratings, nil
+}
+
+// FindReputation sums up the ratings of the user and counts the trades the
+// user completed as either party.
+func (r *RepositoryRating) FindReputation(ctx context.Context, userID uuid.UUID) (ReputationData, error) {
+	q := `
+		SELECT
+			(SELECT avg(score)::float8 FROM public.trade_rating WHERE ratee_id = $1),
+			(SELECT count(*) FROM public.trade_rating WHERE ratee_id = $1),
+			(
+				SELECT count(*)
+				FROM public.trade
+				WHERE
+					status = 'completed'
+				AND
+					(user_id = $1 OR accepted_by = $1)
+			)
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var rep ReputationData
+	if err := r.client.QueryRow(ctx, q, userID).Scan(&rep.Average, &rep.Count, &rep.CompletedTrades); err != nil {
+		return ReputationData{}, err
+	}
+
+	return rep, nil
+}
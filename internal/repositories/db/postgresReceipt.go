@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"go-server/internal/config"
+	"go-server/internal/receipt"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+var ErrReceiptNotFound = errors.New("receipt not found")
+
+// receiptVersion is bumped whenever the layout of the signed payload changes.
+const receiptVersion = 1
+
+type RepositoryReceipt struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+// TradeReceiptData is a stored receipt. Payload holds the exact signed bytes
+// and Signature their base64 encoded Ed25519 signature.
+type TradeReceiptData struct {
+	TradeID        uuid.UUID  `json:"trade_id"`
+	OwnerID        uuid.UUID  `json:"owner_id"`
+	CounterpartyID *uuid.UUID `json:"counterparty_id,omitempty"`
+	RecipientID    *uuid.UUID `json:"recipient_id,omitempty"`
+	Payload        string     `json:"payload"`
+	Signature      string     `json:"signature"`
+	KeyID          string     `json:"key_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// receiptPayload is what gets signed: the parties of a completed trade and
+// the items they swapped as they were at completion.
+type receiptPayload struct {
+	Version        int           `json:"version"`
+	TradeID        uuid.UUID     `json:"trade_id"`
+	OwnerID        uuid.UUID     `json:"owner_id"`
+	CounterpartyID *uuid.UUID    `json:"counterparty_id"`
+	RecipientID    *uuid.UUID    `json:"recipient_id,omitempty"`
+	OfferedItems   []receiptItem `json:"offered_items"`
+	RequestedItems []receiptItem `json:"requested_items"`
+	CreatedAt      time.Time     `json:"created_at"`
+	CompletedAt    time.Time     `json:"completed_at"`
+	KeyID          string        `json:"key_id"`
+}
+
+type receiptItem struct {
+	ItemID   uuid.UUID `json:"item_id"`
+	Name     string    `json:"name"`
+	Rarity   string    `json:"rarity"`
+	Quality  string    `json:"quality"`
+	Quantity int       `json:"quantity"`
+}
+
+func NewRepositoryReceipt(logger *logging.Logger) *RepositoryReceipt {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryReceipt{
+		client: client,
+		logger: logger,
+	}
+}
+
+func (r *RepositoryReceipt) FindOne(ctx context.Context, tradeID string) (TradeReceiptData, error) {
+	q := `
+		SELECT
+			trade_id,
+			owner_id,
+			counterparty_id,
+			recipient_id,
+			payload,
+			signature,
+			key_id,
+			created_at
+		FROM public.trade_receipt
+		WHERE
+			trade_id = $1
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var data TradeReceiptData
+	err := r.client.QueryRow(ctx, q, tradeID).Scan(
+		&data.TradeID,
+		&data.OwnerID,
+		&data.CounterpartyID,
+		&data.RecipientID,
+		&data.Payload,
+		&data.Signature,
+		&data.KeyID,
+		&data.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TradeReceiptData{}, fmt.Errorf("%w: %s", ErrReceiptNotFound, tradeID)
+		}
+		return TradeReceiptData{}, err
+	}
+
+	return data, nil
+}
+
+// issueTradeReceipt signs and stores the receipt of a trade that is being
+// completed within tx. Items are described by the snapshots of the trade
+// lines, so the receipt shows what the parties agreed on. It fails
+// with receipt.ErrNoSigningKey when no key is configured.
+func issueTradeReceipt(ctx context.Context, tx pgx.Tx, tradeID uuid.UUID) error {
+	_, keyID, err := receipt.PublicKey()
+	if err != nil {
+		return err
+	}
+
+	q := `
+		SELECT
+			user_id,
+			accepted_by,
+			recipient_id,
+			date,
+			CURRENT_TIMESTAMP
+		FROM public.trade
+		WHERE
+			id = $1
+	`
+
+	p := receiptPayload{
+		Version:        receiptVersion,
+		TradeID:        tradeID,
+		OfferedItems:   []receiptItem{},
+		RequestedItems: []receiptItem{},
+		KeyID:          keyID,
+	}
+	if err := tx.QueryRow(ctx, q, tradeID).Scan(&p.OwnerID, &p.CounterpartyID, &p.RecipientID, &p.CreatedAt, &p.CompletedAt); err != nil {
+		return err
+	}
+	p.CreatedAt = p.CreatedAt.UTC()
+	p.CompletedAt = p.CompletedAt.UTC()
+
+	q = `
+		SELECT
+			ti.item_id,
+			COALESCE(ti.item_name, i.name, ''),
+			COALESCE(ti.item_rarity, i.rarity, ''),
+			COALESCE(ti.item_quality, i.quality, ''),
+			ti.quantity,
+			ti.item_status
+		FROM public.trade_item ti
+		LEFT JOIN public.item i ON i.id = ti.item_id
+		WHERE
+			ti.trade_id = $1
+		ORDER BY ti.item_id
+	`
+
+	rows, err := tx.Query(ctx, q, tradeID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var it receiptItem
+		var status string
+		if err := rows.Scan(&it.ItemID, &it.Name, &it.Rarity, &it.Quality, &it.Quantity, &status); err != nil {
+			return err
+		}
+		if status == "offered" {
+			p.OfferedItems = append(p.OfferedItems, it)
+		} else {
+			p.RequestedItems = append(p.RequestedItems, it)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	payload, err := receipt.Canonical(p)
+	if err != nil {
+		return err
+	}
+	signature, _, err := receipt.Sign(payload)
+	if err != nil {
+		return err
+	}
+
+	q = `
+		INSERT INTO public.trade_receipt (
+			trade_id,
+			owner_id,
+			counterparty_id,
+			recipient_id,
+			payload,
+			signature,
+			key_id,
+			created_at
+		)
+		VALUES (
+			$1,
+			$2,
+			$3,
+			$4,
+			$5,
+			$6,
+			$7,
+			$8
+		)
+	`
+	_, err = tx.Exec(ctx, q, tradeID, p.OwnerID, p.CounterpartyID, p.RecipientID,
+		string(payload), base64.StdEncoding.EncodeToString(signature), keyID, p.CompletedAt)
+	return err
+}
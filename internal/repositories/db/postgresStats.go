@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+type RepositoryStats struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+// ItemVolumeData is how often an item changed hands in completed trades.
+type ItemVolumeData struct {
+	ItemID   uuid.UUID `json:"item_id"`
+	Name     string    `json:"name"`
+	Rarity   string    `json:"rarity"`
+	Trades   int       `json:"trades"`
+	Quantity int       `json:"quantity"`
+}
+
+// TradeStatusCountData is the number of trades created on a day that are now
+// in the given status.
+type TradeStatusCountData struct {
+	Day    time.Time `json:"day"`
+	Status string    `json:"status"`
+	Trades int       `json:"trades"`
+}
+
+// AcceptanceStatsData describes how long trades stayed pending before they
+// were accepted. The durations are nil when no trade was accepted.
+type AcceptanceStatsData struct {
+	Accepted       int      `json:"accepted"`
+	AverageSeconds *float64 `json:"average_seconds"`
+	MedianSeconds  *float64 `json:"median_seconds"`
+}
+
+// TraderStatsData counts distinct users: active ones did anything on a trade,
+// offering ones created trades and accepting ones accepted them.
+type TraderStatsData struct {
+	Active    int `json:"active"`
+	Offering  int `json:"offering"`
+	Accepting int `json:"accepting"`
+}
+
+func NewRepositoryStats(logger *logging.Logger) *RepositoryStats {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryStats{
+		client: client,
+		logger: logger,
+	}
+}
+
+// Refresh recomputes the materialized views behind the statistics, see
+// migrations/021_create_trade_stats_views.sql. Readers are not blocked.
+func (r *RepositoryStats) Refresh(ctx context.Context) error {
+	for _, view := range []string{"public.trade_status_daily", "public.item_trade_volume_daily"} {
+		q := fmt.Sprintf(`REFRESH MATERIALIZED VIEW CONCURRENTLY %s`, view)
+		r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+		if _, err := r.client.Exec(ctx, q); err != nil {
+			return fmt.Errorf("refresh %s: %w", view, err)
+		}
+	}
+
+	return nil
+}
+
+// FindTopItems returns up to limit items with the most completed trades
+// between from (inclusive) and to (exclusive).
+func (r *RepositoryStats) FindTopItems(ctx context.Context, from, to time.Time, limit int) ([]ItemVolumeData, error) {
+	q := `
+		SELECT
+			v.item_id,
+			i.name,
+			i.rarity,
+			sum(v.trades),
+			sum(v.quantity)
+		FROM public.item_trade_volume_daily v
+		JOIN public.item i ON i.id = v.item_id
+		WHERE
+			v.day >= $1::date
+		AND
+			v.day < $2::date
+		GROUP BY v.item_id, i.name, i.rarity
+		ORDER BY sum(v.trades) DESC, sum(v.quantity) DESC, v.item_id
+		LIMIT $3
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, from, to, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByPos[ItemVolumeData])
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// FindTradeCounts returns the number of trades per creation day and status
+// between from (inclusive) and to (exclusive), oldest day first.
+func (r *RepositoryStats) FindTradeCounts(ctx context.Context, from, to time.Time) ([]TradeStatusCountData, error) {
+	q := `
+		SELECT
+			day,
+			status,
+			trades
+		FROM public.trade_status_daily
+		WHERE
+			day >= $1::date
+		AND
+			day < $2::date
+		ORDER BY day, status
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := pgx.CollectRows(rows, pgx.RowToStructByPos[TradeStatusCountData])
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// FindAcceptanceTime measures the time from creation to acceptance of the
+// trades accepted between from (inclusive) and to (exclusive).
+func (r *RepositoryStats) FindAcceptanceTime(ctx context.Context, from, to time.Time) (*AcceptanceStatsData, error) {
+	q := `
+		SELECT
+			count(*),
+			avg(extract(epoch FROM a.accepted_at - t.date)),
+			percentile_cont(0.5) WITHIN GROUP (ORDER BY extract(epoch FROM a.accepted_at - t.date))
+		FROM (
+			SELECT
+				trade_id,
+				min(created_at) AS accepted_at
+			FROM public.trade_event
+			WHERE
+				event_type = 'status_changed'
+			AND
+				payload->>'to' = 'accepted'
+			GROUP BY trade_id
+		) a
+		JOIN public.trade t ON t.id = a.trade_id
+		WHERE
+			a.accepted_at >= $1
+		AND
+			a.accepted_at < $2
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var stats AcceptanceStatsData
+	if err := r.client.QueryRow(ctx, q, from, to).Scan(&stats.Accepted, &stats.AverageSeconds, &stats.MedianSeconds); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
+
+// FindTraderCounts counts the users who took part in trades between from
+// (inclusive) and to (exclusive).
+func (r *RepositoryStats) FindTraderCounts(ctx context.Context, from, to time.Time) (*TraderStatsData, error) {
+	q := `
+		SELECT
+			count(DISTINCT actor_id),
+			count(DISTINCT actor_id) FILTER (WHERE event_type = 'created'),
+			count(DISTINCT actor_id) FILTER (WHERE event_type = 'status_changed' AND payload->>'to' = 'accepted')
+		FROM public.trade_event
+		WHERE
+			actor_id IS NOT NULL
+		AND
+			created_at >= $1
+		AND
+			created_at < $2
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var stats TraderStatsData
+	if err := r.client.QueryRow(ctx, q, from, to).Scan(&stats.Active, &stats.Offering, &stats.Accepting); err != nil {
+		return nil, err
+	}
+
+	return &stats, nil
+}
@@ -2,35 +2,205 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 
 	"go-server/internal/config"
+	"go-server/internal/receipt"
 	"go-server/pkg/client/postgresql"
 	"go-server/pkg/logging"
 
 )
 
+// ErrTradeStatusChanged is returned when a trade is no longer in the status
+// a status update expected, usually because another request changed it first.
+var ErrTradeStatusChanged = errors.New("trade status changed")
+
+var ErrTradeNotFound = errors.New("trade not found")
+
+// ErrTradeNotPending is returned when a trade that is no longer pending is
+// changed as if it still were.
+var ErrTradeNotPending = errors.New("trade is not pending")
+
+// ErrTradeInProgress is returned when an accepted or completed trade is
+// deleted: its items are moving or have moved between the parties.
+var ErrTradeInProgress = errors.New("accepted and completed trades cannot be deleted")
+
+// ErrTradeVersionConflict is returned when an update was based on a stale
+// version of the trade.
+var ErrTradeVersionConflict = errors.New("trade was modified by another request")
+
+// PendingLimitError is returned by Create when the owner of the new trade
+// already has Limit pending trades.
+type PendingLimitError struct {
+	Pending int
+	Limit   int
+}
+
+func (e *PendingLimitError) Error() string {
+	return fmt.Sprintf("%d trades are already pending, at most %d are allowed", e.Pending, e.Limit)
+}
+
+// tradeValueJoin estimates both sides of trade t from the reference market
+// prices of their items into the "val" relation. A side holding an item
+// without a known price has no value, and so has the fairness of the trade:
+// the ratio of the cheaper side to the dearer one, 1 being an even swap.
+const tradeValueJoin = `
+		LEFT JOIN LATERAL (
+			SELECT
+				v.offered_value,
+				v.requested_value,
+				CASE
+					WHEN greatest(v.offered_value, v.requested_value) > 0
+					THEN least(v.offered_value, v.requested_value) / greatest(v.offered_value, v.requested_value)
+				END AS fairness
+			FROM (
+				SELECT
+					CASE
+						WHEN count(*) FILTER (WHERE vi.item_status = 'offered' AND p.reference_price IS NULL) = 0
+						THEN COALESCE(sum(vi.quantity * p.reference_price) FILTER (WHERE vi.item_status = 'offered'), 0)
+					END AS offered_value,
+					CASE
+						WHEN count(*) FILTER (WHERE vi.item_status = 'requested' AND p.reference_price IS NULL) = 0
+						THEN COALESCE(sum(vi.quantity * p.reference_price) FILTER (WHERE vi.item_status = 'requested'), 0)
+					END AS requested_value
+				FROM public.trade_item vi
+				JOIN public.item i ON i.id = vi.item_id
+				LEFT JOIN public.item_price p ON p.name = i.name
+				WHERE
+					vi.trade_id = t.id
+			) v
+		) val ON TRUE
+`
+
+// tradeSelect is the projection shared by all queries returning trades
+// together with their items. Rows must be read with collectTrades.
+const tradeSelect = tradeColumns + `
+			val.offered_value,
+			val.requested_value,
+			val.fairness,` + tradeItemColumns + tradeValueJoin + tradeItemJoin
+
+// tradeUnvaluedSelect is tradeSelect without the value estimate, which is
+// left NULL, for queries over many trades that have no use for it.
+const tradeUnvaluedSelect = tradeColumns + `
+			NULL::float8,
+			NULL::float8,
+			NULL::float8,` + tradeItemColumns + tradeItemJoin
+
+const tradeColumns = `
+		SELECT
+			t.id,
+			t.user_id,
+			t.parent_id,
+			t.accepted_by,
+			t.recipient_id,
+			t.status,
+			t.date,
+			t.expires_at,
+			t.version,`
+
+const tradeItemColumns = `
+			EXISTS (
+				SELECT 1 FROM public.trade_review rv WHERE rv.trade_id = t.id AND rv.status = 'open'
+			) AS under_review,
+			ti.item_id,
+			ti.item_status,
+			ti.quantity,
+			ti.item_name,
+			ti.item_rarity,
+			ti.item_quality
+		FROM public.trade t`
+
+const tradeItemJoin = `
+		LEFT JOIN public.trade_item ti ON t.id = ti.trade_id
+`
+
+// tradeThreadCTE resolves the negotiation thread of trade $1 into the "thread"
+// relation: it walks up to the root offer and then collects all its descendants.
+const tradeThreadCTE = `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM public.trade WHERE id = $1
+			UNION ALL
+			SELECT p.id, p.parent_id FROM public.trade p JOIN ancestors a ON p.id = a.parent_id
+		), thread AS (
+			SELECT id FROM ancestors WHERE parent_id IS NULL
+			UNION ALL
+			SELECT c.id FROM public.trade c JOIN thread th ON c.parent_id = th.id
+		)
+`
+
 type RepositoryTrade struct {
 	client postgresql.Client
 	logger *logging.Logger
 }
 
 type TradeData struct {
-	TradeID        uuid.UUID   `json:"trade_id"`
-	UserID         uuid.UUID   `json:"user_id"`
-	Status         string      `json:"status"`
-	Date           time.Time   `json:"date"`
-	OfferedItems   []TradeItem `json:"offered_items"`
-	RequestedItems []TradeItem `json:"requested_items"`
+	TradeID        uuid.UUID        `json:"trade_id"`
+	UserID         uuid.UUID        `json:"user_id"`
+	ParentID       *uuid.UUID       `json:"parent_id,omitempty"`
+	AcceptedBy     *uuid.UUID       `json:"accepted_by,omitempty"`
+	RecipientID    *uuid.UUID       `json:"recipient_id,omitempty"`
+	Status         string           `json:"status"`
+	Date           time.Time        `json:"date"`
+	ExpiresAt      *time.Time       `json:"expires_at,omitempty"`
+	Version        int              `json:"version"`
+	OfferedValue   *float64         `json:"offered_value,omitempty"`
+	RequestedValue *float64         `json:"requested_value,omitempty"`
+	Fairness       *float64         `json:"fairness,omitempty"`
+	UnderReview    bool             `json:"under_review"`
+	OfferedItems   []TradeItem      `json:"offered_items"`
+	RequestedItems []TradeItem      `json:"requested_items"`
+	ActorID        uuid.UUID        `json:"-"` // user performing the change, recorded in trade_event
+	Review         *TradeReviewData `json:"-"` // set by Create and Update callers to hold the trade for review
+	PendingLimit   int              `json:"-"` // set by Create callers to cap the pending trades of the owner, 0 for no cap
+}
+
+// TradeFilter narrows and pages the trades returned by FindPage. Zero values
+// disable the corresponding condition. Private trades are only returned to
+// their parties (ViewerID) unless IncludePrivate is set.
+type TradeFilter struct {
+	ViewerID        uuid.UUID
+	IncludePrivate  bool
+	Status          string
+	UserID          uuid.UUID
+	OfferedItemID   uuid.UUID
+	RequestedItemID uuid.UUID
+	Rarity          string
+	CreatedFrom     *time.Time
+	CreatedTo       *time.Time
+	MinFairness     *float64
+	MinReputation   *float64
+	Descending      bool
+	After           *TradeCursor
+	Limit           int
 }
 
+// TradeCursor points at the last trade of a page in (date, id) order.
+type TradeCursor struct {
+	Date    time.Time
+	TradeID uuid.UUID
+}
+
+// TradeItem is one line of a trade: Quantity copies of the same item on the
+// offered or requested side.
 type TradeItem struct {
-	ItemID     uuid.UUID `json:"item_id"`
-	ItemStatus string    `json:"item_status"`
+	ItemID     uuid.UUID     `json:"item_id"`
+	ItemStatus string        `json:"item_status"`
+	Quantity   int           `json:"quantity"`
+	Snapshot   *ItemSnapshot `json:"snapshot,omitempty" db:"-"` // filled by trade reads, nil for lines older than snapshots
+}
+
+// ItemSnapshot holds the attributes of an item at the time it was put into a
+// trade line.
+type ItemSnapshot struct {
+	Name    *string `json:"name"`
+	Rarity  *string `json:"rarity"`
+	Quality *string `json:"quality"`
 }
 
 func NewRepositoryTrade(logger *logging.Logger) *RepositoryTrade {
@@ -47,7 +217,7 @@ func NewRepositoryTrade(logger *logging.Logger) *RepositoryTrade {
 	}
 }
 
-func (r *RepositoryTrade) Create(ctx context.Context, data TradeData) (interface{}, error) {
+func (r *RepositoryTrade) Create(ctx context.Context, data TradeData) (_ interface{}, err error) {
 	tx, err := r.client.Begin(ctx)
 	if err != nil {
 		return nil, err
@@ -57,105 +227,94 @@ func (r *RepositoryTrade) Create(ctx context.Context, data TradeData) (interface
 			_ = tx.Rollback(ctx)
 			return
 		}
-		_ = tx.Commit(ctx)
+		err = tx.Commit(ctx)
 	}()
 
-	tradeID, err := r.createTrade(ctx, tx, data)
+	if data.PendingLimit > 0 {
+		if err = r.checkPendingLimit(ctx, tx, data.UserID, data.PendingLimit); err != nil {
+			return nil, err
+		}
+	}
+
+	if data.ParentID != nil {
+		var settles bool
+		if settles, err = auctionTrade(ctx, tx, *data.ParentID); err != nil {
+			return nil, err
+		}
+		if settles {
+			return nil, fmt.Errorf("%w: %s", ErrAuctionTrade, *data.ParentID)
+		}
+	}
+
+	tradeID, err := r.insertTrade(ctx, tx, data)
 	if err != nil {
-		r.logger.Infof("Failed to create trade: %v", data)
 		return nil, err
 	}
 
-	if err := r.createTradeItems(ctx, tx, tradeID, append(data.OfferedItems, data.RequestedItems...)); err != nil {
-		r.logger.Errorf("Failed to create trade items: %v", err)
-		return nil, err
+	if data.Review != nil {
+		if err = openTradeReview(ctx, tx, tradeID, data.Review); err != nil {
+			return nil, err
+		}
 	}
 
 	r.logger.Infof("Completed to create trade: %v", data)
 	return tradeID, nil
 }
 
-func (r *RepositoryTrade) FindAll(ctx context.Context) ([]TradeData, error) {
-	q := `
-        SELECT 
-			t.id,
-			t.user_id,
-			t.status,
-			t.date,
-			ti.item_id,
-			ti.item_status
-		FROM public.trade t
-		LEFT JOIN public.trade_item ti ON t.id = ti.trade_id
-	`
-	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
-
-	rows, err := r.client.Query(ctx, q)
+// insertTrade stores a new trade with its items within tx, puts the offered
+// items in escrow, records the creation in the trade history and notifies
+// the users wishing for the offered items.
+func (r *RepositoryTrade) insertTrade(ctx context.Context, tx pgx.Tx, data TradeData) (uuid.UUID, error) {
+	tradeID, err := r.createTrade(ctx, tx, data)
 	if err != nil {
-		return nil, err
+		r.logger.Infof("Failed to create trade: %v", data)
+		return uuid.Nil, err
 	}
 
-	defer rows.Close()
-
-	tradesMap := make(map[uuid.UUID]TradeData)
-	for rows.Next() {
-		var td TradeData
-		var itemID uuid.UUID
-		var itemStatus string
+	if err := r.createTradeItems(ctx, tx, tradeID, append(data.OfferedItems, data.RequestedItems...)); err != nil {
+		r.logger.Errorf("Failed to create trade items: %v", err)
+		return uuid.Nil, err
+	}
 
-		if err := rows.Scan(&td.TradeID, &td.UserID, &td.Status, &td.Date, &itemID, &itemStatus); err != nil {
-			return nil, err
-		}
+	if err := r.reserveTradeItems(ctx, tx, tradeID, data.UserID, data.OfferedItems); err != nil {
+		r.logger.Infof("Failed to reserve offered items: %v", err)
+		return uuid.Nil, err
+	}
 
-		if itemID != uuid.Nil {
-			item := TradeItem{ItemID: itemID, ItemStatus: itemStatus}
-			if existingTrade, ok := tradesMap[td.TradeID]; ok {
-				if item.ItemStatus == "offered" {
-					existingTrade.OfferedItems = append(existingTrade.OfferedItems, item)
-				} else if item.ItemStatus == "requested" {
-					existingTrade.RequestedItems = append(existingTrade.RequestedItems, item)
-				} else {
-					r.logger.Fatalf("Item status %s is not supported", item.ItemStatus)
-				}
-				tradesMap[td.TradeID] = existingTrade
-
-			} else {
-				if item.ItemStatus == "offered" {
-					td.OfferedItems = []TradeItem{item}
-				} else if item.ItemStatus == "requested" {
-					td.RequestedItems = []TradeItem{item}
-				} else {
-					r.logger.Fatalf("Item status %s is not supported", item.ItemStatus)
-				}
-				tradesMap[td.TradeID] = td
-			}
-		}
+	created := struct {
+		tradeItemsSnapshot
+		UserID      uuid.UUID  `json:"user_id"`
+		ParentID    *uuid.UUID `json:"parent_id,omitempty"`
+		RecipientID *uuid.UUID `json:"recipient_id,omitempty"`
+	}{tradeItemsSnapshot{data.OfferedItems, data.RequestedItems}, data.UserID, data.ParentID, data.RecipientID}
+	if err := recordTradeEvent(ctx, tx, tradeID, TradeEventCreated, data.ActorID, created); err != nil {
+		return uuid.Nil, err
 	}
 
-	var trades []TradeData
-	for _, trade := range tradesMap {
-		trades = append(trades, trade)
+	if err := notifyWishlists(ctx, tx, tradeID); err != nil {
+		r.logger.Infof("Failed to notify wishlists: %v", err)
+		return uuid.Nil, err
 	}
 
-	if err := rows.Err(); err != nil {
+	return tradeID, nil
+}
+
+func (r *RepositoryTrade) FindAll(ctx context.Context) ([]TradeData, error) {
+	q := tradeSelect + `
+		ORDER BY t.date, t.id
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q)
+	if err != nil {
 		return nil, err
 	}
 
-	return trades, nil
+	return r.collectTrades(rows)
 }
 
 func (r *RepositoryTrade) FindOne(ctx context.Context, tradeID string) (TradeData, error) {
-	q := `
-        SELECT 
-			t.id,
-			t.user_id,
-			t.status,
-			t.date,
-			ti.item_id,
-			ti.item_status
-		FROM public.trade t
-		JOIN public.trade_item ti 
-		ON 
-			t.id = ti.trade_id
+	q := tradeSelect + `
 		WHERE
 			t.id = $1
 	`
@@ -166,45 +325,19 @@ func (r *RepositoryTrade) FindOne(ctx context.Context, tradeID string) (TradeDat
 		return TradeData{}, err
 	}
 
-	defer rows.Close()
-
-	var trade TradeData
-	for rows.Next() {
-		var itemID uuid.UUID
-		var itemStatus string
-
-		if err := rows.Scan(&trade.TradeID, &trade.UserID, &trade.Status, &trade.Date, &itemID, &itemStatus); err != nil {
-			return TradeData{}, err
-		}
-
-		item := TradeItem{ItemID: itemID, ItemStatus: itemStatus}
-		if item.ItemStatus == "offered" {
-			trade.OfferedItems = append(trade.OfferedItems, item)
-		} else if item.ItemStatus == "requested" {
-			trade.RequestedItems = append(trade.RequestedItems, item)
-		} else {
-			r.logger.Fatalf("Item status %s is not supported", item.ItemStatus)
-		}
-	}
-
-	if err := rows.Err(); err != nil {
+	trades, err := r.collectTrades(rows)
+	if err != nil {
 		return TradeData{}, err
 	}
+	if len(trades) == 0 {
+		return TradeData{}, nil
+	}
 
-	return trade, nil
+	return trades[0], nil
 }
 
 func (r *RepositoryTrade) FindByItemUUID(ctx context.Context, itemID string) ([]TradeData, error) {
-	q := `
-		SELECT 
-			t.id,
-    		t.user_id,
-    		t.status,
-			t.date,
-			ti.item_id,
-			ti.item_status
-		FROM public.trade t 
-		JOIN public.trade_item ti ON t.id = ti.trade_id
+	q := tradeSelect + `
 		WHERE EXISTS (
 			SELECT 
 				1
@@ -214,6 +347,7 @@ func (r *RepositoryTrade) FindByItemUUID(ctx context.Context, itemID string) ([]
 			AND 
 				ti_sub.item_id = $1
 			)
+		ORDER BY t.date, t.id
 	`
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
@@ -222,54 +356,161 @@ func (r *RepositoryTrade) FindByItemUUID(ctx context.Context, itemID string) ([]
 		return nil, err
 	}
 
-	defer rows.Close()
-
-	tradesMap := make(map[uuid.UUID]TradeData)
-	for rows.Next() {
-		var td TradeData
-		var itemID uuid.UUID
-		var itemStatus string
+	return r.collectTrades(rows)
+}
 
-		if err := rows.Scan(&td.TradeID, &td.UserID, &td.Status, &td.Date, &itemID, &itemStatus); err != nil {
-			return nil, err
+// FindPage returns one page of trades matching the filter, ordered by
+// creation date with the trade ID as a tie-breaker.
+func (r *RepositoryTrade) FindPage(ctx context.Context, filter TradeFilter) ([]TradeData, error) {
+	var conds []string
+	var args []any
+	where := func(cond string, values ...any) {
+		placeholders := make([]any, len(values))
+		for i, v := range values {
+			args = append(args, v)
+			placeholders[i] = len(args)
 		}
+		conds = append(conds, fmt.Sprintf(cond, placeholders...))
+	}
 
-		item := TradeItem{ItemID: itemID, ItemStatus: itemStatus}
-		if existingTrade, ok := tradesMap[td.TradeID]; ok {
-			if item.ItemStatus == "offered" {
-				existingTrade.OfferedItems = append(existingTrade.OfferedItems, item)
-			} else if item.ItemStatus == "requested" {
-				existingTrade.RequestedItems = append(existingTrade.RequestedItems, item)
-			} else {
-				r.logger.Fatalf("Item status %s is not supported", item.ItemStatus)
-			}
-			tradesMap[td.TradeID] = existingTrade
+	if !filter.IncludePrivate {
+		if filter.ViewerID != uuid.Nil {
+			where("(t.recipient_id IS NULL OR t.user_id = $%d OR t.recipient_id = $%d)", filter.ViewerID, filter.ViewerID)
+		} else {
+			where("t.recipient_id IS NULL")
+		}
+	}
+	if filter.Status != "" {
+		where("t.status = $%d", filter.Status)
+	}
+	if filter.UserID != uuid.Nil {
+		where("t.user_id = $%d", filter.UserID)
+	}
+	if filter.OfferedItemID != uuid.Nil {
+		where("EXISTS (SELECT 1 FROM public.trade_item f WHERE f.trade_id = t.id AND f.item_status = 'offered' AND f.item_id = $%d)", filter.OfferedItemID)
+	}
+	if filter.RequestedItemID != uuid.Nil {
+		where("EXISTS (SELECT 1 FROM public.trade_item f WHERE f.trade_id = t.id AND f.item_status = 'requested' AND f.item_id = $%d)", filter.RequestedItemID)
+	}
+	if filter.Rarity != "" {
+		where("EXISTS (SELECT 1 FROM public.trade_item f JOIN public.item i ON i.id = f.item_id WHERE f.trade_id = t.id AND lower(i.rarity) = lower($%d))", filter.Rarity)
+	}
+	if filter.CreatedFrom != nil {
+		where("t.date >= $%d", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		where("t.date < $%d", *filter.CreatedTo)
+	}
+	if filter.MinReputation != nil {
+		where("(SELECT avg(rt.score) FROM public.trade_rating rt WHERE rt.ratee_id = t.user_id) >= $%d", *filter.MinReputation)
+	}
+	joins := ""
+	if filter.MinFairness != nil {
+		joins = tradeValueJoin
+		where("val.fairness >= $%d", *filter.MinFairness)
+	}
 
+	order := "t.date, t.id"
+	if filter.Descending {
+		order = "t.date DESC, t.id DESC"
+	}
+	if filter.After != nil {
+		if filter.Descending {
+			where("(t.date, t.id) < ($%d, $%d)", filter.After.Date, filter.After.TradeID)
 		} else {
-			if item.ItemStatus == "offered" {
-				td.OfferedItems = []TradeItem{item}
-			} else if item.ItemStatus == "requested" {
-				td.RequestedItems = []TradeItem{item}
-			} else {
-				r.logger.Fatalf("Item status %s is not supported", item.ItemStatus)
-			}
-			tradesMap[td.TradeID] = td
+			where("(t.date, t.id) > ($%d, $%d)", filter.After.Date, filter.After.TradeID)
 		}
 	}
 
-	var trades []TradeData
-	for _, trade := range tradesMap {
-		trades = append(trades, trade)
+	cond := "TRUE"
+	if len(conds) > 0 {
+		cond = strings.Join(conds, " AND ")
 	}
 
-	if err := rows.Err(); err != nil {
+	args = append(args, filter.Limit)
+	q := fmt.Sprintf(`
+		WITH page AS (
+			SELECT
+				t.id
+			FROM public.trade t%s
+			WHERE
+				%s
+			ORDER BY %s
+			LIMIT $%d
+		)`, joins, cond, order, len(args)) + tradeSelect + fmt.Sprintf(`
+		WHERE
+			t.id IN (SELECT id FROM page)
+		ORDER BY %s
+	`, order)
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, args...)
+	if err != nil {
 		return nil, err
 	}
 
-	return trades, nil
+	return r.collectTrades(rows)
 }
 
-func (r *RepositoryTrade) Update(ctx context.Context, trade interface{}) (interface{}, error) {
+// FindMatchCandidates returns the pending trades of other users that could
+// swap with target: those offering an item target requests or requesting an
+// item target offers. Their value is not estimated.
+func (r *RepositoryTrade) FindMatchCandidates(ctx context.Context, target TradeData) ([]TradeData, error) {
+	q := tradeUnvaluedSelect + `
+		WHERE
+			t.status = 'pending'
+		AND
+			t.user_id <> $1
+		AND
+			t.id IN (
+				SELECT
+					m.trade_id
+				FROM public.trade_item m
+				WHERE
+					(m.item_status = 'offered' AND m.item_id = ANY($2))
+				OR
+					(m.item_status = 'requested' AND m.item_id = ANY($3))
+			)
+		ORDER BY t.date, t.id
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	requested := make([]uuid.UUID, 0, len(target.RequestedItems))
+	for _, item := range target.RequestedItems {
+		requested = append(requested, item.ItemID)
+	}
+	offered := make([]uuid.UUID, 0, len(target.OfferedItems))
+	for _, item := range target.OfferedItems {
+		offered = append(offered, item.ItemID)
+	}
+
+	rows, err := r.client.Query(ctx, q, target.UserID, requested, offered)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.collectTrades(rows)
+}
+
+// FindThread returns every trade of the negotiation thread the given trade
+// belongs to: the root offer and all counter-offers below it.
+func (r *RepositoryTrade) FindThread(ctx context.Context, tradeID string) ([]TradeData, error) {
+	q := tradeThreadCTE + tradeSelect + `
+		WHERE
+			t.id IN (SELECT id FROM thread)
+		ORDER BY t.date, t.id
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, tradeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.collectTrades(rows)
+}
+
+func (r *RepositoryTrade) Update(ctx context.Context, trade interface{}) (_ interface{}, err error) {
 	updatedTrade := trade.(TradeData)
 
 	tx, err := r.client.Begin(ctx)
@@ -281,37 +522,228 @@ func (r *RepositoryTrade) Update(ctx context.Context, trade interface{}) (interf
 			_ = tx.Rollback(ctx)
 			return
 		}
-		_ = tx.Commit(ctx)
+		err = tx.Commit(ctx)
 	}()
 
-	if err := r.updateTrade(ctx, tx, updatedTrade); err != nil {
+	if err = r.updateTrade(ctx, tx, updatedTrade); err != nil {
 		r.logger.Infof("Failed to update trade: %v", updatedTrade)
 		return nil, err
 	}
 
-	if err := r.updateTradeItems(ctx, tx, updatedTrade.TradeID, append(updatedTrade.OfferedItems, updatedTrade.RequestedItems...)); err != nil {
+	var settles bool
+	if settles, err = auctionTrade(ctx, tx, updatedTrade.TradeID); err != nil {
+		return nil, err
+	}
+	if settles {
+		return nil, fmt.Errorf("%w: %s", ErrAuctionTrade, updatedTrade.TradeID)
+	}
+
+	previous, err := r.findTradeItems(ctx, tx, updatedTrade.TradeID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.updateTradeItems(ctx, tx, updatedTrade.TradeID, append(updatedTrade.OfferedItems, updatedTrade.RequestedItems...)); err != nil {
+		return nil, err
+	}
+
+	if err = releaseInventoryItems(ctx, tx, updatedTrade.TradeID.String()); err != nil {
 		return nil, err
 	}
 
+	if err = r.reserveTradeItems(ctx, tx, updatedTrade.TradeID, updatedTrade.UserID, updatedTrade.OfferedItems); err != nil {
+		r.logger.Infof("Failed to reserve offered items: %v", err)
+		return nil, err
+	}
+
+	changed := struct {
+		Previous tradeItemsSnapshot `json:"previous"`
+		Current  tradeItemsSnapshot `json:"current"`
+	}{splitTradeItems(previous), tradeItemsSnapshot{updatedTrade.OfferedItems, updatedTrade.RequestedItems}}
+	if err = recordTradeEvent(ctx, tx, updatedTrade.TradeID, TradeEventItemsChanged, updatedTrade.ActorID, changed); err != nil {
+		return nil, err
+	}
+
+	if updatedTrade.Review != nil {
+		if err = openTradeReview(ctx, tx, updatedTrade.TradeID, updatedTrade.Review); err != nil {
+			return nil, err
+		}
+	}
+
 	r.logger.Infof("Completed to update trade: %v", updatedTrade)
 	return nil, nil
 }
 
-func (r *RepositoryTrade) GetTradesByUserUUID(ctx context.Context, userID string) ([]TradeData, error) {
+// UpdateStatus moves a trade from one status to another in a single
+// transaction together with the side effects of the new status. actorID is
+// the user causing the change and is recorded as the counterparty on accept.
+// A trade held for review can be neither accepted nor completed; a non-nil
+// review holds the trade once the change is made.
+func (r *RepositoryTrade) UpdateStatus(ctx context.Context, tradeID, from, to string, actorID uuid.UUID, review *TradeReviewData) (err error) {
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	if to == "accepted" || to == "completed" {
+		var held bool
+		if held, err = tradeUnderReview(ctx, tx, tradeID); err != nil {
+			return err
+		}
+		if held {
+			return fmt.Errorf("%w: %s", ErrTradeUnderReview, tradeID)
+		}
+	}
+
+	if err = r.updateStatus(ctx, tx, tradeID, from, to); err != nil {
+		return err
+	}
+
+	id, err := uuid.Parse(tradeID)
+	if err != nil {
+		return err
+	}
+	if err = recordTradeEvent(ctx, tx, id, TradeEventStatusChanged, actorID, statusChange{From: from, To: to}); err != nil {
+		return err
+	}
+
+	switch to {
+	case "accepted":
+		if err = r.setAcceptedBy(ctx, tx, tradeID, actorID); err != nil {
+			return err
+		}
+		var closed []uuid.UUID
+		if closed, err = r.closeThreadSiblings(ctx, tx, tradeID); err != nil {
+			r.logger.Errorf("Failed to close negotiation thread of trade %s: %v", tradeID, err)
+			return err
+		}
+		if err = recordStatusEvents(ctx, tx, closed, statusChange{From: "pending", To: "rejected", Reason: "sibling_accepted"}); err != nil {
+			return err
+		}
+	case "completed":
+		if err = r.transferTradeItems(ctx, tx, tradeID); err != nil {
+			r.logger.Errorf("Failed to transfer items of trade %s: %v", tradeID, err)
+			return err
+		}
+		if err = settleAuctionTrade(ctx, tx, id, true); err != nil {
+			return err
+		}
+		if err = issueTradeReceipt(ctx, tx, id); err != nil {
+			if !errors.Is(err, receipt.ErrNoSigningKey) {
+				return err
+			}
+			r.logger.Errorf("Trade %s completed without a receipt: %v", tradeID, err)
+			err = nil
+		}
+	case "rejected", "cancelled", "expired":
+		if err = releaseInventoryItems(ctx, tx, tradeID); err != nil {
+			return err
+		}
+		if err = settleAuctionTrade(ctx, tx, id, false); err != nil {
+			return err
+		}
+	}
+
+	if review != nil {
+		if err = openTradeReview(ctx, tx, id, review); err != nil {
+			return err
+		}
+	}
+
+	r.logger.Infof("Completed to change trade %s status: %s -> %s", tradeID, from, to)
+	return nil
+}
+
+// ExpirePending moves up to limit pending trades whose expires_at is before
+// now to the "expired" status and releases their escrow. It returns the IDs
+// of the expired trades; rows locked by other transactions are skipped.
+func (r *RepositoryTrade) ExpirePending(ctx context.Context, now time.Time, limit int) (_ []uuid.UUID, err error) {
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
 	q := `
-        SELECT 
-			t.id,
-			t.user_id,
-			t.status,
-			t.date,
-			ti.item_id,
-			ti.item_status
-		FROM public.trade t 
-		JOIN public.trade_item ti 
-		ON 
-			t.id = ti.trade_id
+		WITH due AS (
+			SELECT
+				id
+			FROM public.trade
+			WHERE
+				status = 'pending'
+			AND
+				expires_at < $1
+			ORDER BY expires_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE public.trade t
+		SET
+			status = 'expired',
+			version = t.version + 1
+		FROM due
+		WHERE
+			t.id = due.id
+		RETURNING t.id
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := tx.Query(ctx, q, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	q = `
+		UPDATE public.inventory
+		SET
+			trade_id = NULL
+		WHERE
+			trade_id = ANY($1)
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	if _, err = tx.Exec(ctx, q, ids); err != nil {
+		return nil, err
+	}
+
+	if err = recordStatusEvents(ctx, tx, ids, statusChange{From: "pending", To: "expired", Reason: "ttl"}); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if err = settleAuctionTrade(ctx, tx, id, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *RepositoryTrade) GetTradesByUserUUID(ctx context.Context, userID string) ([]TradeData, error) {
+	q := tradeSelect + `
 		WHERE 
 			t.user_id = $1
+		ORDER BY t.date, t.id
 	`
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
@@ -320,51 +752,303 @@ func (r *RepositoryTrade) GetTradesByUserUUID(ctx context.Context, userID string
 		return nil, err
 	}
 
+	return r.collectTrades(rows)
+}
+
+// FindIncoming returns the private trades addressed to the user, newest first.
+func (r *RepositoryTrade) FindIncoming(ctx context.Context, userID string) ([]TradeData, error) {
+	q := tradeSelect + `
+		WHERE
+			t.recipient_id = $1
+		ORDER BY t.date DESC, t.id DESC
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.collectTrades(rows)
+}
+
+// FindOutgoing returns the private trades the user sent, newest first.
+func (r *RepositoryTrade) FindOutgoing(ctx context.Context, userID string) ([]TradeData, error) {
+	q := tradeSelect + `
+		WHERE
+			t.user_id = $1
+		AND
+			t.recipient_id IS NOT NULL
+		ORDER BY t.date DESC, t.id DESC
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.collectTrades(rows)
+}
+
+// checkPendingLimit fails with a *PendingLimitError when the user already
+// has limit pending trades. It locks the user for the rest of tx, so
+// concurrent creates of the same user are counted one after the other.
+func (r *RepositoryTrade) checkPendingLimit(ctx context.Context, tx pgx.Tx, userID uuid.UUID, limit int) error {
+	q := `
+		SELECT pg_advisory_xact_lock(hashtext($1))
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	if _, err := tx.Exec(ctx, q, userID.String()); err != nil {
+		return err
+	}
+
+	q = `
+		SELECT
+			count(*)
+		FROM public.trade
+		WHERE
+			user_id = $1
+		AND
+			status = 'pending'
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var n int
+	if err := tx.QueryRow(ctx, q, userID).Scan(&n); err != nil {
+		return err
+	}
+	if n >= limit {
+		return &PendingLimitError{Pending: n, Limit: limit}
+	}
+
+	return nil
+}
+
+// collectTrades folds rows selected with tradeSelect into trades, keeping the
+// order in which the trades first appear.
+func (r *RepositoryTrade) collectTrades(rows pgx.Rows) ([]TradeData, error) {
 	defer rows.Close()
 
-	tradesMap := make(map[uuid.UUID]TradeData)
+	trades := make([]TradeData, 0)
+	index := make(map[uuid.UUID]int)
 	for rows.Next() {
 		var td TradeData
-		var itemID uuid.UUID
-		var itemStatus string
+		var itemID *uuid.UUID
+		var itemStatus *string
+		var quantity *int
+		var snapshot ItemSnapshot
 
-		if err := rows.Scan(&td.TradeID, &td.UserID, &td.Status, &td.Date, &itemID, &itemStatus); err != nil {
+		if err := rows.Scan(&td.TradeID, &td.UserID, &td.ParentID, &td.AcceptedBy, &td.RecipientID, &td.Status, &td.Date, &td.ExpiresAt, &td.Version, &td.OfferedValue, &td.RequestedValue, &td.Fairness, &td.UnderReview, &itemID, &itemStatus, &quantity, &snapshot.Name, &snapshot.Rarity, &snapshot.Quality); err != nil {
 			return nil, err
 		}
 
-		item := TradeItem{ItemID: itemID, ItemStatus: itemStatus}
-		if existingTrade, ok := tradesMap[td.TradeID]; ok {
-			if item.ItemStatus == "offered" {
-				existingTrade.OfferedItems = append(existingTrade.OfferedItems, item)
-			} else if item.ItemStatus == "requested" {
-				existingTrade.RequestedItems = append(existingTrade.RequestedItems, item)
-			} else {
-				r.logger.Fatalf("Item status %s is not supported", item.ItemStatus)
-			}
-			tradesMap[td.TradeID] = existingTrade
+		i, ok := index[td.TradeID]
+		if !ok {
+			i = len(trades)
+			index[td.TradeID] = i
+			trades = append(trades, td)
+		}
 
+		if itemID == nil {
+			continue
+		}
+
+		item := TradeItem{ItemID: *itemID, ItemStatus: *itemStatus, Quantity: *quantity}
+		if snapshot.Name != nil {
+			item.Snapshot = &snapshot
+		}
+		if item.ItemStatus == "offered" {
+			trades[i].OfferedItems = append(trades[i].OfferedItems, item)
+		} else if item.ItemStatus == "requested" {
+			trades[i].RequestedItems = append(trades[i].RequestedItems, item)
 		} else {
-			if item.ItemStatus == "offered" {
-				td.OfferedItems = []TradeItem{item}
-			} else if item.ItemStatus == "requested" {
-				td.RequestedItems = []TradeItem{item}
-			} else {
-				r.logger.Fatalf("Item status %s is not supported", item.ItemStatus)
+			r.logger.Fatalf("Item status %s is not supported", item.ItemStatus)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return trades, nil
+}
+
+func (r *RepositoryTrade) updateStatus(ctx context.Context, tx pgx.Tx, tradeID, from, to string) error {
+	q := `
+		UPDATE public.trade
+		SET
+			status = $3,
+			version = version + 1
+		WHERE
+			id = $1
+		AND
+			status = $2
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tag, err := tx.Exec(ctx, q, tradeID, from, to)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTradeStatusChanged
+	}
+
+	return nil
+}
+
+func (r *RepositoryTrade) setAcceptedBy(ctx context.Context, tx pgx.Tx, tradeID string, userID uuid.UUID) error {
+	q := `
+		UPDATE public.trade
+		SET
+			accepted_by = $2
+		WHERE
+			id = $1
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	if _, err := tx.Exec(ctx, q, tradeID, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// transferTradeItems swaps the items of a completed trade between its owner
+// and the user who accepted it: the offered instances held in escrow go to the
+// counterparty and free instances of the requested items go to the owner.
+func (r *RepositoryTrade) transferTradeItems(ctx context.Context, tx pgx.Tx, tradeID string) error {
+	q := `
+		SELECT
+			user_id,
+			accepted_by
+		FROM public.trade
+		WHERE
+			id = $1
+		FOR UPDATE
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var ownerID uuid.UUID
+	var counterpartyID *uuid.UUID
+	if err := tx.QueryRow(ctx, q, tradeID).Scan(&ownerID, &counterpartyID); err != nil {
+		return err
+	}
+	if counterpartyID == nil {
+		return fmt.Errorf("trade %s has no counterparty", tradeID)
+	}
+
+	items, err := r.findTradeItems(ctx, tx, tradeID)
+	if err != nil {
+		return err
+	}
+
+	var offered int64
+	for _, item := range items {
+		if item.ItemStatus == "offered" {
+			offered += int64(item.Quantity)
+			continue
+		}
+		for n := 0; n < item.Quantity; n++ {
+			if err := transferInventoryItem(ctx, tx, item.ItemID, *counterpartyID, ownerID); err != nil {
+				return err
 			}
-			tradesMap[td.TradeID] = td
 		}
 	}
 
-	var trades []TradeData
-	for _, trade := range tradesMap {
-		trades = append(trades, trade)
+	moved, err := transferReservedItems(ctx, tx, tradeID, *counterpartyID)
+	if err != nil {
+		return err
+	}
+	if moved != offered {
+		return fmt.Errorf("%w: trade %s holds %d of %d offered items in escrow", ErrItemNotOwned, tradeID, moved, offered)
 	}
 
-	if err := rows.Err(); err != nil {
+	return nil
+}
+
+func (r *RepositoryTrade) findTradeItems(ctx context.Context, tx pgx.Tx, tradeID string) ([]TradeItem, error) {
+	q := `
+		SELECT
+			item_id,
+			item_status,
+			quantity
+		FROM public.trade_item
+		WHERE
+			trade_id = $1
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := tx.Query(ctx, q, tradeID)
+	if err != nil {
 		return nil, err
 	}
 
-	return trades, nil
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[TradeItem])
+}
+
+func splitTradeItems(items []TradeItem) tradeItemsSnapshot {
+	var snapshot tradeItemsSnapshot
+	for _, item := range items {
+		if item.ItemStatus == "offered" {
+			snapshot.OfferedItems = append(snapshot.OfferedItems, item)
+		} else {
+			snapshot.RequestedItems = append(snapshot.RequestedItems, item)
+		}
+	}
+	return snapshot
+}
+
+// reserveTradeItems puts Quantity instances of every offered item in escrow
+// for the trade, so the same instances cannot be offered in another pending
+// trade.
+func (r *RepositoryTrade) reserveTradeItems(ctx context.Context, tx pgx.Tx, tradeID, userID uuid.UUID, items []TradeItem) error {
+	for _, item := range items {
+		for n := 0; n < item.Quantity; n++ {
+			if err := reserveInventoryItem(ctx, tx, tradeID, userID, item.ItemID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// closeThreadSiblings rejects every other pending offer in the negotiation
+// thread of an accepted trade, releases the items they held in escrow and
+// returns the IDs of the rejected offers.
+func (r *RepositoryTrade) closeThreadSiblings(ctx context.Context, tx pgx.Tx, tradeID string) ([]uuid.UUID, error) {
+	q := tradeThreadCTE + `, closed AS (
+			UPDATE public.trade
+			SET
+				status = 'rejected',
+				version = version + 1
+			WHERE
+				id IN (SELECT id FROM thread)
+			AND
+				id <> $1
+			AND
+				status = 'pending'
+			RETURNING id
+		), released AS (
+			UPDATE public.inventory
+			SET
+				trade_id = NULL
+			WHERE
+				trade_id IN (SELECT id FROM closed)
+		)
+		SELECT id FROM closed
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := tx.Query(ctx, q, tradeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
 }
 
 func (r *RepositoryTrade) createTrade(ctx context.Context, tx pgx.Tx, data TradeData) (uuid.UUID, error) {
@@ -372,18 +1056,24 @@ func (r *RepositoryTrade) createTrade(ctx context.Context, tx pgx.Tx, data Trade
 		INSERT INTO public.trade (
 			id,
 			user_id,
+			parent_id,
+			recipient_id,
 			status,
-			date)
+			date,
+			expires_at)
 		VALUES (
 			gen_random_uuid(),
 			$1,
 			$2,
-			CURRENT_TIMESTAMP)
+			$3,
+			$4,
+			CURRENT_TIMESTAMP,
+			$5)
 		RETURNING id
 	`
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	if err := tx.QueryRow(ctx, q, data.UserID, data.Status).Scan(&data.TradeID); err != nil {
+	if err := tx.QueryRow(ctx, q, data.UserID, data.ParentID, data.RecipientID, data.Status, data.ExpiresAt).Scan(&data.TradeID); err != nil {
 		return uuid.Nil, err
 	}
 
@@ -396,17 +1086,25 @@ func (r *RepositoryTrade) createTradeItems(ctx context.Context, tx pgx.Tx, trade
 			id,
 			trade_id,
 			item_id,
-			item_status)
+			item_status,
+			quantity,
+			item_name,
+			item_rarity,
+			item_quality)
 		VALUES (
 			gen_random_uuid(),
 			$1,
 			$2,
-			$3)
+			$3,
+			$4,
+			(SELECT name FROM public.item WHERE id = $2),
+			(SELECT rarity FROM public.item WHERE id = $2),
+			(SELECT quality FROM public.item WHERE id = $2))
 		RETURNING id
 	`
 
 	for _, item := range items {
-		if _, err := tx.Exec(ctx, q, tradeID, item.ItemID, item.ItemStatus); err != nil {
+		if _, err := tx.Exec(ctx, q, tradeID, item.ItemID, item.ItemStatus, item.Quantity); err != nil {
 			r.logger.Errorf("Failed to insert trade item: %v", err)
 			return err
 		}
@@ -415,21 +1113,48 @@ func (r *RepositoryTrade) createTradeItems(ctx context.Context, tx pgx.Tx, trade
 	return nil
 }
 
+// updateTrade bumps the version of a pending trade if it is still at
+// data.Version. Trades that are no longer pending cannot be changed.
 func (r *RepositoryTrade) updateTrade(ctx context.Context, tx pgx.Tx, data TradeData) error {
 	q := `
 		UPDATE public.trade
 		SET
-			user_id = $1,
-			status = $2,
-			date = $3
+			version = version + 1
 		WHERE
-			id = $4
+			id = $1
+		AND
+			status = 'pending'
+		AND
+			version = $2
 	`
 	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
 
-	if _, err := tx.Exec(ctx, q, data.UserID, data.Status, data.Date, data.TradeID); err != nil {
+	tag, err := tx.Exec(ctx, q, data.TradeID, data.Version)
+	if err != nil {
 		return err
 	}
+	if tag.RowsAffected() == 0 {
+		q = `
+			SELECT
+				status
+			FROM public.trade
+			WHERE
+				id = $1
+		`
+		r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+		var status string
+		if err := tx.QueryRow(ctx, q, data.TradeID).Scan(&status); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrTradeNotFound
+			}
+			return err
+		}
+		if status != "pending" {
+			return fmt.Errorf("%w: %s", ErrTradeNotPending, status)
+		}
+		return ErrTradeVersionConflict
+	}
 
 	return nil
 }
@@ -449,17 +1174,25 @@ func (r *RepositoryTrade) updateTradeItems(ctx context.Context, tx pgx.Tx, trade
 			id,
 			trade_id,
 			item_id,
-			item_status)
+			item_status,
+			quantity,
+			item_name,
+			item_rarity,
+			item_quality)
 		VALUES (
 			gen_random_uuid(),
 			$1,
 			$2,
-			$3)
+			$3,
+			$4,
+			(SELECT name FROM public.item WHERE id = $2),
+			(SELECT rarity FROM public.item WHERE id = $2),
+			(SELECT quality FROM public.item WHERE id = $2))
 		RETURNING id
 	`
 
 	for _, item := range items {
-		if _, err := tx.Exec(ctx, q, tradeID, item.ItemID, item.ItemStatus); err != nil {
+		if _, err := tx.Exec(ctx, q, tradeID, item.ItemID, item.ItemStatus, item.Quantity); err != nil {
 			return err
 		}
 	}
@@ -467,7 +1200,7 @@ func (r *RepositoryTrade) updateTradeItems(ctx context.Context, tx pgx.Tx, trade
 	return nil
 }
 
-func (r *RepositoryTrade) Delete(ctx context.Context, tradeID string) error {
+func (r *RepositoryTrade) Delete(ctx context.Context, tradeID string, actorID uuid.UUID) (err error) {
 	tx, err := r.client.Begin(ctx)
 	if err != nil {
 		return err
@@ -477,14 +1210,57 @@ func (r *RepositoryTrade) Delete(ctx context.Context, tradeID string) error {
 			_ = tx.Rollback(ctx)
 			return
 		}
-		_ = tx.Commit(ctx)
+		err = tx.Commit(ctx)
 	}()
 
-	if err := r.deleteTradeItems(ctx, tx, tradeID); err != nil {
+	id, err := uuid.Parse(tradeID)
+	if err != nil {
+		return err
+	}
+
+	q := `
+		SELECT
+			status
+		FROM public.trade
+		WHERE
+			id = $1
+		FOR UPDATE
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var status string
+	if err = tx.QueryRow(ctx, q, id).Scan(&status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrTradeNotFound
+		}
+		return err
+	}
+	if status == "accepted" || status == "completed" {
+		return fmt.Errorf("%w: %s", ErrTradeInProgress, status)
+	}
+
+	items, err := r.findTradeItems(ctx, tx, tradeID)
+	if err != nil {
+		return err
+	}
+
+	deleted := struct {
+		tradeItemsSnapshot
+		Status string `json:"status"`
+	}{splitTradeItems(items), status}
+	if err = recordTradeEvent(ctx, tx, id, TradeEventDeleted, actorID, deleted); err != nil {
+		return err
+	}
+
+	if err = settleAuctionTrade(ctx, tx, id, false); err != nil {
+		return err
+	}
+
+	if err = r.deleteTradeItems(ctx, tx, tradeID); err != nil {
 		return err
 	}
 
-	if err := r.deleteTrade(ctx, tx, tradeID); err != nil {
+	if err = r.deleteTrade(ctx, tx, tradeID); err != nil {
 		return err
 	}
 
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+const (
+	TradeEventCreated        = "created"
+	TradeEventItemsChanged   = "items_changed"
+	TradeEventStatusChanged  = "status_changed"
+	TradeEventDeleted        = "deleted"
+	TradeEventReviewOpened   = "review_opened"
+	TradeEventReviewResolved = "review_resolved"
+)
+
+type RepositoryTradeEvent struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+type TradeEventData struct {
+	ID        uuid.UUID       `json:"id"`
+	TradeID   uuid.UUID       `json:"trade_id"`
+	EventType string          `json:"event_type"`
+	ActorID   *uuid.UUID      `json:"actor_id,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// tradeItemsSnapshot is the payload describing the terms of a trade.
+type tradeItemsSnapshot struct {
+	OfferedItems   []TradeItem `json:"offered_items"`
+	RequestedItems []TradeItem `json:"requested_items"`
+}
+
+type statusChange struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func NewRepositoryTradeEvent(logger *logging.Logger) *RepositoryTradeEvent {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryTradeEvent{
+		client: client,
+		logger: logger,
+	}
+}
+
+func (r *RepositoryTradeEvent) FindByTradeID(ctx context.Context, tradeID string) ([]TradeEventData, error) {
+	q := `
+		SELECT
+			id,
+			trade_id,
+			event_type,
+			actor_id,
+			payload,
+			created_at
+		FROM public.trade_event
+		WHERE
+			trade_id = $1
+		ORDER BY created_at, seq
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, tradeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]TradeEventData, 0)
+	for rows.Next() {
+		var ev TradeEventData
+
+		if err := rows.Scan(&ev.ID, &ev.TradeID, &ev.EventType, &ev.ActorID, &ev.Payload, &ev.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		events = append(events, ev)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// recordTradeEvent appends an event to the history of a trade within tx.
+// A nil actorID records a system event.
+func recordTradeEvent(ctx context.Context, tx pgx.Tx, tradeID uuid.UUID, eventType string, actorID uuid.UUID, payload any) error {
+	q := `
+		INSERT INTO public.trade_event (
+			id,
+			trade_id,
+			event_type,
+			actor_id,
+			payload,
+			created_at
+		)
+		VALUES (
+			gen_random_uuid(),
+			$1,
+			$2,
+			$3,
+			$4,
+			CURRENT_TIMESTAMP
+		)
+	`
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, q, tradeID, eventType, nullableUUID(actorID), body); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// recordStatusEvents appends the same system status change to the history of
+// every given trade within tx.
+func recordStatusEvents(ctx context.Context, tx pgx.Tx, tradeIDs []uuid.UUID, change statusChange) error {
+	q := `
+		INSERT INTO public.trade_event (
+			id,
+			trade_id,
+			event_type,
+			actor_id,
+			payload,
+			created_at
+		)
+		SELECT
+			gen_random_uuid(),
+			trade_id,
+			$2,
+			NULL,
+			$3,
+			CURRENT_TIMESTAMP
+		FROM unnest($1::uuid[]) AS trade_id
+	`
+
+	if len(tradeIDs) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, q, tradeIDs, TradeEventStatusChanged, body); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func nullableUUID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
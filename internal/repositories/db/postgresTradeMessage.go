@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+type RepositoryTradeMessage struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+type TradeMessageData struct {
+	ID        uuid.UUID `json:"id"`
+	TradeID   uuid.UUID `json:"trade_id"`
+	SenderID  uuid.UUID `json:"sender_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TradeMessageCursor points at the last message of a page in (created_at, id)
+// order.
+type TradeMessageCursor struct {
+	CreatedAt time.Time
+	MessageID uuid.UUID
+}
+
+func NewRepositoryTradeMessage(logger *logging.Logger) *RepositoryTradeMessage {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryTradeMessage{
+		client: client,
+		logger: logger,
+	}
+}
+
+func (r *RepositoryTradeMessage) Create(ctx context.Context, data TradeMessageData) (TradeMessageData, error) {
+	q := `
+		INSERT INTO public.trade_message (
+			id,
+			trade_id,
+			sender_id,
+			body,
+			created_at
+		)
+		VALUES (
+			gen_random_uuid(),
+			$1,
+			$2,
+			$3,
+			CURRENT_TIMESTAMP
+		)
+		RETURNING id, created_at
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	if err := r.client.QueryRow(ctx, q, data.TradeID, data.SenderID, data.Body).Scan(&data.ID, &data.CreatedAt); err != nil {
+		return TradeMessageData{}, err
+	}
+
+	return data, nil
+}
+
+// FindPage returns up to limit messages of the trade, oldest first, starting
+// after the cursor when one is given.
+func (r *RepositoryTradeMessage) FindPage(ctx context.Context, tradeID string, after *TradeMessageCursor, limit int) ([]TradeMessageData, error) {
+	q := `
+		SELECT
+			id,
+			trade_id,
+			sender_id,
+			body,
+			created_at
+		FROM public.trade_message
+		WHERE
+			trade_id = $1
+		AND
+			($2::timestamptz IS NULL OR (created_at, id) > ($2, $3))
+		ORDER BY created_at, id
+		LIMIT $4
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var afterDate *time.Time
+	var afterID uuid.UUID
+	if after != nil {
+		afterDate = &after.CreatedAt
+		afterID = after.MessageID
+	}
+
+	rows, err := r.client.Query(ctx, q, tradeID, afterDate, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]TradeMessageData, 0)
+	for rows.Next() {
+		var m TradeMessageData
+
+		if err := rows.Scan(&m.ID, &m.TradeID, &m.SenderID, &m.Body, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
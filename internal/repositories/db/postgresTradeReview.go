@@ -0,0 +1,445 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+const (
+	TradeReviewOpen     = "open"
+	TradeReviewApproved = "approved"
+	TradeReviewRejected = "rejected"
+
+	TradeReviewStageCreated  = "created"
+	TradeReviewStageAccepted = "accepted"
+)
+
+var (
+	ErrTradeReviewNotFound = errors.New("trade review not found")
+	ErrTradeReviewResolved = errors.New("trade review is already resolved")
+
+	// ErrTradeUnderReview is returned when a trade held for review is about
+	// to be accepted or completed.
+	ErrTradeUnderReview = errors.New("trade is held for review")
+)
+
+type RepositoryTradeReview struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+// TradeReviewData is the hold put on a trade flagged by the fraud checks.
+// OwnerID, CounterpartyID and TradeStatus describe the trade as it is when
+// the review is read. Reviews outlive deleted trades: their parties then come
+// from the trade history and TradeStatus is "deleted".
+type TradeReviewData struct {
+	ID             uuid.UUID     `json:"review_id"`
+	TradeID        uuid.UUID     `json:"trade_id"`
+	Stage          string        `json:"stage"`
+	Score          int           `json:"score"`
+	Signals        []FraudSignal `json:"signals"`
+	Status         string        `json:"status"`
+	ReviewerID     *uuid.UUID    `json:"reviewer_id,omitempty"`
+	Note           *string       `json:"note,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+	ResolvedAt     *time.Time    `json:"resolved_at,omitempty"`
+	OwnerID        uuid.UUID     `json:"owner_id"`
+	CounterpartyID *uuid.UUID    `json:"counterparty_id,omitempty"`
+	RecipientID    *uuid.UUID    `json:"recipient_id,omitempty"`
+	TradeStatus    string        `json:"trade_status"`
+}
+
+// FraudSignal is one fraud check that fired on a trade and the score it
+// added.
+type FraudSignal struct {
+	Check  string `json:"check"`
+	Score  int    `json:"score"`
+	Detail string `json:"detail"`
+}
+
+type reviewChange struct {
+	ReviewID uuid.UUID `json:"review_id"`
+	Stage    string    `json:"stage,omitempty"`
+	Status   string    `json:"status"`
+}
+
+const tradeReviewSelect = `
+		SELECT
+			rv.id,
+			rv.trade_id,
+			rv.stage,
+			rv.score,
+			rv.signals,
+			rv.status,
+			rv.reviewer_id,
+			rv.note,
+			rv.created_at,
+			rv.resolved_at,
+			COALESCE(t.user_id, ce.user_id),
+			t.accepted_by,
+			COALESCE(t.recipient_id, ce.recipient_id),
+			COALESCE(t.status, 'deleted')
+		FROM public.trade_review rv
+		LEFT JOIN public.trade t ON t.id = rv.trade_id
+		LEFT JOIN LATERAL (
+			SELECT
+				(e.payload->>'user_id')::uuid AS user_id,
+				(e.payload->>'recipient_id')::uuid AS recipient_id
+			FROM public.trade_event e
+			WHERE
+				e.trade_id = rv.trade_id
+			AND
+				e.event_type = 'created'
+			LIMIT 1
+		) ce ON t.id IS NULL
+`
+
+func NewRepositoryTradeReview(logger *logging.Logger) *RepositoryTradeReview {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryTradeReview{
+		client: client,
+		logger: logger,
+	}
+}
+
+// FindByStatus returns up to limit reviews in the given status, highest
+// score first.
+func (r *RepositoryTradeReview) FindByStatus(ctx context.Context, status string, limit int) ([]TradeReviewData, error) {
+	q := tradeReviewSelect + `
+		WHERE
+			rv.status = $1
+		ORDER BY rv.score DESC, rv.created_at, rv.id
+		LIMIT $2
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reviews := make([]TradeReviewData, 0)
+	for rows.Next() {
+		review, err := scanTradeReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return reviews, nil
+}
+
+func (r *RepositoryTradeReview) FindOne(ctx context.Context, reviewID string) (TradeReviewData, error) {
+	q := tradeReviewSelect + `
+		WHERE
+			rv.id = $1
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	review, err := scanTradeReview(r.client.QueryRow(ctx, q, reviewID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TradeReviewData{}, fmt.Errorf("%w: %s", ErrTradeReviewNotFound, reviewID)
+		}
+		return TradeReviewData{}, err
+	}
+
+	return review, nil
+}
+
+// Resolve closes an open review. Rejecting it also rejects the trade, unless
+// the trade is already closed, and releases its escrow.
+func (r *RepositoryTradeReview) Resolve(ctx context.Context, reviewID uuid.UUID, status string, reviewerID uuid.UUID, note *string) (err error) {
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	q := `
+		UPDATE public.trade_review
+		SET
+			status = $2,
+			reviewer_id = $3,
+			note = $4,
+			resolved_at = CURRENT_TIMESTAMP
+		WHERE
+			id = $1
+		AND
+			status = 'open'
+		RETURNING trade_id
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var tradeID uuid.UUID
+	err = tx.QueryRow(ctx, q, reviewID, status, reviewerID, note).Scan(&tradeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var exists bool
+		if err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM public.trade_review WHERE id = $1)`, reviewID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("%w: %s", ErrTradeReviewResolved, reviewID)
+		}
+		return fmt.Errorf("%w: %s", ErrTradeReviewNotFound, reviewID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err = recordTradeEvent(ctx, tx, tradeID, TradeEventReviewResolved, reviewerID, reviewChange{ReviewID: reviewID, Status: status}); err != nil {
+		return err
+	}
+
+	if status != TradeReviewRejected {
+		r.logger.Infof("Completed to approve trade review %s", reviewID)
+		return nil
+	}
+
+	q = `
+		SELECT
+			status
+		FROM public.trade
+		WHERE
+			id = $1
+		FOR UPDATE
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var from string
+	err = tx.QueryRow(ctx, q, tradeID).Scan(&from)
+	if errors.Is(err, pgx.ErrNoRows) {
+		r.logger.Infof("Completed to reject trade review %s, trade %s was deleted", reviewID, tradeID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if from != "pending" && from != "accepted" {
+		r.logger.Infof("Completed to reject trade review %s, trade %s was already %s", reviewID, tradeID, from)
+		return nil
+	}
+
+	trades := &RepositoryTrade{client: r.client, logger: r.logger}
+	if err = trades.updateStatus(ctx, tx, tradeID.String(), from, "rejected"); err != nil {
+		return err
+	}
+	if err = recordTradeEvent(ctx, tx, tradeID, TradeEventStatusChanged, reviewerID, statusChange{From: from, To: "rejected", Reason: "review_rejected"}); err != nil {
+		return err
+	}
+	if err = releaseInventoryItems(ctx, tx, tradeID.String()); err != nil {
+		return err
+	}
+	if err = settleAuctionTrade(ctx, tx, tradeID, false); err != nil {
+		return err
+	}
+
+	r.logger.Infof("Completed to reject trade review %s and trade %s", reviewID, tradeID)
+	return nil
+}
+
+// CountRecentTrades returns how many trades the user created or accepted
+// since the given time.
+func (r *RepositoryTradeReview) CountRecentTrades(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	q := `
+		SELECT
+			(
+				SELECT count(*)
+				FROM public.trade
+				WHERE
+					user_id = $1
+				AND
+					date >= $2::timestamptz
+			) + (
+				SELECT count(*)
+				FROM public.trade_event
+				WHERE
+					actor_id = $1
+				AND
+					created_at >= $2::timestamptz
+				AND
+					event_type = 'status_changed'
+				AND
+					payload->>'to' = 'accepted'
+			)
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var n int
+	if err := r.client.QueryRow(ctx, q, userID, since).Scan(&n); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// CountTradesBetween returns how many trades created since the given time
+// were accepted between the two users, in either direction.
+func (r *RepositoryTradeReview) CountTradesBetween(ctx context.Context, userID, otherID uuid.UUID, since time.Time) (int, error) {
+	q := `
+		SELECT
+			count(*)
+		FROM public.trade
+		WHERE
+			(
+				(user_id = $1 AND accepted_by = $2)
+				OR
+				(user_id = $2 AND accepted_by = $1)
+			)
+		AND
+			status IN ('accepted', 'completed')
+		AND
+			date >= $3
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var n int
+	if err := r.client.QueryRow(ctx, q, userID, otherID, since).Scan(&n); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// CountSharedUserAgent returns how many accounts, the user included, hold a
+// token issued to one of the user agents the user is logged in with.
+func (r *RepositoryTradeReview) CountSharedUserAgent(ctx context.Context, userID uuid.UUID) (int, error) {
+	q := `
+		SELECT
+			count(DISTINCT o.user_id)
+		FROM public.user_token t
+		JOIN public.user_token o ON o.user_agent = t.user_agent
+		WHERE
+			t.user_id = $1
+		AND
+			t.user_agent <> ''
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var n int
+	if err := r.client.QueryRow(ctx, q, userID).Scan(&n); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func scanTradeReview(row pgx.Row) (TradeReviewData, error) {
+	var review TradeReviewData
+	var signals []byte
+	if err := row.Scan(
+		&review.ID,
+		&review.TradeID,
+		&review.Stage,
+		&review.Score,
+		&signals,
+		&review.Status,
+		&review.ReviewerID,
+		&review.Note,
+		&review.CreatedAt,
+		&review.ResolvedAt,
+		&review.OwnerID,
+		&review.CounterpartyID,
+		&review.RecipientID,
+		&review.TradeStatus,
+	); err != nil {
+		return TradeReviewData{}, err
+	}
+
+	if err := json.Unmarshal(signals, &review.Signals); err != nil {
+		return TradeReviewData{}, err
+	}
+	return review, nil
+}
+
+// openTradeReview holds the trade for review within tx. A trade already
+// held keeps its open review.
+func openTradeReview(ctx context.Context, tx pgx.Tx, tradeID uuid.UUID, review *TradeReviewData) error {
+	q := `
+		INSERT INTO public.trade_review (
+			id,
+			trade_id,
+			stage,
+			score,
+			signals,
+			status,
+			created_at
+		)
+		VALUES (
+			gen_random_uuid(),
+			$1,
+			$2,
+			$3,
+			$4,
+			'open',
+			CURRENT_TIMESTAMP
+		)
+		ON CONFLICT (trade_id) WHERE status = 'open' DO NOTHING
+		RETURNING id
+	`
+
+	signals, err := json.Marshal(review.Signals)
+	if err != nil {
+		return err
+	}
+
+	var reviewID uuid.UUID
+	err = tx.QueryRow(ctx, q, tradeID, review.Stage, review.Score, signals).Scan(&reviewID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return recordTradeEvent(ctx, tx, tradeID, TradeEventReviewOpened, uuid.Nil, reviewChange{ReviewID: reviewID, Stage: review.Stage, Status: TradeReviewOpen})
+}
+
+// tradeUnderReview reports whether the trade has an open review.
+func tradeUnderReview(ctx context.Context, tx pgx.Tx, tradeID string) (bool, error) {
+	q := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM public.trade_review
+			WHERE
+				trade_id = $1
+			AND
+				status = 'open'
+		)
+	`
+
+	var held bool
+	if err := tx.QueryRow(ctx, q, tradeID).Scan(&held); err != nil {
+		return false, err
+	}
+
+	return held, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -215,3 +216,22 @@ func (r *RepositoryUser) UpdateUserRole(ctx context.Context, id, role string) er
 	return nil
 
 }
+
+// FindCreatedAt returns when the account of the user was created.
+func (r *RepositoryUser) FindCreatedAt(ctx context.Context, id string) (time.Time, error) {
+	q := `
+		SELECT
+			created_at
+		FROM public.user
+		WHERE
+			id = $1
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	var createdAt time.Time
+	if err := r.client.QueryRow(ctx, q, id).Scan(&createdAt); err != nil {
+		return time.Time{}, err
+	}
+
+	return createdAt, nil
+}
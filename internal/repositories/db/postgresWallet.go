@@ -0,0 +1,355 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// Ledger account kinds, see migrations/019_create_ledger_tables.sql.
+const (
+	AccountWallet   = "wallet"
+	AccountEscrow   = "escrow"
+	AccountExternal = "external"
+)
+
+// Ledger transaction kinds.
+const (
+	LedgerDeposit         = "deposit"
+	LedgerWithdrawal      = "withdrawal"
+	LedgerListingSale     = "listing_sale"
+	LedgerBuyOrderHold    = "buy_order_hold"
+	LedgerBuyOrderFill    = "buy_order_fill"
+	LedgerBuyOrderRelease = "buy_order_release"
+	LedgerBidHold         = "bid_hold"
+	LedgerBidRelease      = "bid_release"
+	LedgerAuctionSale     = "auction_sale"
+)
+
+type RepositoryWallet struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+// WalletBalance is the state of a user's accounts in one currency. Held
+// funds are locked by open buy orders and auction bids.
+type WalletBalance struct {
+	Currency  string  `json:"currency"`
+	Available float64 `json:"available"`
+	Held      float64 `json:"held"`
+}
+
+// LedgerEntryData is one movement on a user's wallet or escrow account.
+type LedgerEntryData struct {
+	TransactionID uuid.UUID  `json:"transaction_id"`
+	Kind          string     `json:"kind"`
+	ReferenceID   *uuid.UUID `json:"reference_id,omitempty"`
+	Memo          *string    `json:"memo,omitempty"`
+	Account       string     `json:"account"`
+	Currency      string     `json:"currency"`
+	Amount        float64    `json:"amount"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// ledgerTransfer describes a balanced ledger transaction: the amounts of its
+// postings sum up to zero.
+type ledgerTransfer struct {
+	Kind        string
+	Currency    string
+	ReferenceID *uuid.UUID
+	CreatedBy   *uuid.UUID
+	Memo        string
+	Postings    []ledgerPosting
+}
+
+// ledgerPosting credits (positive amount) or debits (negative amount) an
+// account of a user.
+type ledgerPosting struct {
+	UserID  uuid.UUID
+	Account string
+	Amount  float64
+}
+
+func NewRepositoryWallet(logger *logging.Logger) *RepositoryWallet {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryWallet{
+		client: client,
+		logger: logger,
+	}
+}
+
+// Adjust credits (positive amount) or debits (negative amount) the wallet of
+// userID against the external account, simulating a deposit or withdrawal.
+func (r *RepositoryWallet) Adjust(ctx context.Context, userID uuid.UUID, amount float64, currency, memo string, createdBy uuid.UUID) (_ uuid.UUID, err error) {
+	tx, err := r.client.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		err = tx.Commit(ctx)
+	}()
+
+	kind := LedgerDeposit
+	if amount < 0 {
+		kind = LedgerWithdrawal
+	}
+
+	id, err := postLedger(ctx, tx, ledgerTransfer{
+		Kind:      kind,
+		Currency:  currency,
+		CreatedBy: &createdBy,
+		Memo:      memo,
+		Postings: []ledgerPosting{
+			{UserID: userID, Account: AccountWallet, Amount: amount},
+			{UserID: uuid.Nil, Account: AccountExternal, Amount: -amount},
+		},
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	r.logger.Infof("Completed to adjust wallet of user %s: %.2f %s", userID, amount, currency)
+	return id, nil
+}
+
+// FindBalances returns the balances of a user per currency.
+func (r *RepositoryWallet) FindBalances(ctx context.Context, userID string) ([]WalletBalance, error) {
+	q := `
+		SELECT
+			currency,
+			COALESCE(sum(balance) FILTER (WHERE kind = 'wallet'), 0),
+			COALESCE(sum(balance) FILTER (WHERE kind = 'escrow'), 0)
+		FROM public.ledger_account
+		WHERE
+			user_id = $1
+		AND
+			kind IN ('wallet', 'escrow')
+		GROUP BY currency
+		ORDER BY currency
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	balances, err := pgx.CollectRows(rows, pgx.RowToStructByPos[WalletBalance])
+	if err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+// FindEntries returns up to limit ledger entries of a user's accounts, newest
+// first.
+func (r *RepositoryWallet) FindEntries(ctx context.Context, userID string, limit int) ([]LedgerEntryData, error) {
+	q := `
+		SELECT
+			t.id,
+			t.kind,
+			t.reference_id,
+			t.memo,
+			a.kind,
+			a.currency,
+			e.amount,
+			e.created_at
+		FROM public.ledger_entry e
+		JOIN public.ledger_account a ON a.id = e.account_id
+		JOIN public.ledger_transaction t ON t.id = e.transaction_id
+		WHERE
+			a.user_id = $1
+		AND
+			a.kind IN ('wallet', 'escrow')
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT $2
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := pgx.CollectRows(rows, pgx.RowToStructByPos[LedgerEntryData])
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// postLedger records a balanced transaction and applies its postings to the
+// account balances inside tx, so the balance change commits or rolls back
+// together with the operation that caused it. Accounts are created on first
+// use. A debit that would make a wallet or escrow balance negative fails with
+// ErrInsufficientFunds.
+func postLedger(ctx context.Context, tx pgx.Tx, t ledgerTransfer) (uuid.UUID, error) {
+	postings, err := balancePostings(t)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if len(postings) == 0 {
+		return uuid.Nil, nil
+	}
+
+	q := `
+		INSERT INTO public.ledger_transaction (
+			id,
+			kind,
+			reference_id,
+			currency,
+			memo,
+			created_by,
+			created_at
+		)
+		VALUES (
+			gen_random_uuid(),
+			$1,
+			$2,
+			$3,
+			NULLIF($4, ''),
+			$5,
+			CURRENT_TIMESTAMP
+		)
+		RETURNING id
+	`
+
+	var id uuid.UUID
+	if err = tx.QueryRow(ctx, q, t.Kind, t.ReferenceID, t.Currency, t.Memo, t.CreatedBy).Scan(&id); err != nil {
+		return uuid.Nil, err
+	}
+
+	for _, p := range postings {
+		accountID, err := applyPosting(ctx, tx, p, t.Currency)
+		if err != nil {
+			return uuid.Nil, err
+		}
+
+		q = `
+			INSERT INTO public.ledger_entry (
+				id,
+				transaction_id,
+				account_id,
+				amount,
+				created_at
+			)
+			VALUES (
+				gen_random_uuid(),
+				$1,
+				$2,
+				$3,
+				CURRENT_TIMESTAMP
+			)
+		`
+		if _, err := tx.Exec(ctx, q, id, accountID, p.Amount); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	return id, nil
+}
+
+// balancePostings rounds the postings of t to cents, drops zero postings and
+// checks that the rest sum up to zero. The postings are returned sorted by
+// account and user, so concurrent transfers between the same accounts update
+// them in the same order and cannot deadlock.
+func balancePostings(t ledgerTransfer) ([]ledgerPosting, error) {
+	var postings []ledgerPosting
+	var sum int64
+	for _, p := range t.Postings {
+		cents := int64(math.Round(p.Amount * 100))
+		if cents == 0 {
+			continue
+		}
+		sum += cents
+		postings = append(postings, ledgerPosting{UserID: p.UserID, Account: p.Account, Amount: float64(cents) / 100})
+	}
+	if sum != 0 {
+		return nil, fmt.Errorf("unbalanced %s ledger transaction: %d cents", t.Kind, sum)
+	}
+
+	sort.Slice(postings, func(i, j int) bool {
+		if postings[i].Account != postings[j].Account {
+			return postings[i].Account < postings[j].Account
+		}
+		return postings[i].UserID.String() < postings[j].UserID.String()
+	})
+	return postings, nil
+}
+
+// applyPosting adds the posting amount to the account balance and returns
+// the account ID. The account is created empty first: a debit on a new row
+// would trip the balance check before ON CONFLICT gets a chance to merge it.
+func applyPosting(ctx context.Context, tx pgx.Tx, p ledgerPosting, currency string) (uuid.UUID, error) {
+	q := `
+		INSERT INTO public.ledger_account (
+			id,
+			user_id,
+			kind,
+			currency,
+			balance,
+			created_at
+		)
+		VALUES (
+			gen_random_uuid(),
+			$1,
+			$2,
+			$3,
+			0,
+			CURRENT_TIMESTAMP
+		)
+		ON CONFLICT (kind, user_id, currency) DO NOTHING
+	`
+	if _, err := tx.Exec(ctx, q, p.UserID, p.Account, currency); err != nil {
+		return uuid.Nil, err
+	}
+
+	q = `
+		UPDATE public.ledger_account
+		SET
+			balance = balance + $4
+		WHERE
+			user_id = $1
+		AND
+			kind = $2
+		AND
+			currency = $3
+		RETURNING id
+	`
+
+	var id uuid.UUID
+	if err := tx.QueryRow(ctx, q, p.UserID, p.Account, currency, p.Amount).Scan(&id); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "ledger_account_funds_check" {
+			return uuid.Nil, fmt.Errorf("%w: %s account of user %s needs %.2f %s", ErrInsufficientFunds, p.Account, p.UserID, -p.Amount, currency)
+		}
+		return uuid.Nil, err
+	}
+
+	return id, nil
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestBalancePostings(t *testing.T) {
+	buyer, seller := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name      string
+		postings  []ledgerPosting
+		wantCount int
+		wantErr   bool
+	}{
+		{
+			name: "balanced transfer",
+			postings: []ledgerPosting{
+				{UserID: buyer, Account: AccountWallet, Amount: -9.99},
+				{UserID: seller, Account: AccountWallet, Amount: 9.99},
+			},
+			wantCount: 2,
+		},
+		{
+			name: "amounts are rounded to cents",
+			postings: []ledgerPosting{
+				{UserID: buyer, Account: AccountWallet, Amount: -0.1 - 0.2},
+				{UserID: seller, Account: AccountWallet, Amount: 0.3},
+			},
+			wantCount: 2,
+		},
+		{
+			name: "zero postings are dropped",
+			postings: []ledgerPosting{
+				{UserID: buyer, Account: AccountWallet, Amount: 0.001},
+				{UserID: seller, Account: AccountWallet, Amount: 0},
+			},
+			wantCount: 0,
+		},
+		{
+			name: "unbalanced transfer",
+			postings: []ledgerPosting{
+				{UserID: buyer, Account: AccountWallet, Amount: -10},
+				{UserID: seller, Account: AccountWallet, Amount: 9.99},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sub-cent imbalance",
+			postings: []ledgerPosting{
+				{UserID: buyer, Account: AccountEscrow, Amount: -5.005},
+				{UserID: seller, Account: AccountWallet, Amount: 5},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			postings, err := balancePostings(ledgerTransfer{Kind: "test", Postings: tt.postings})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got postings %+v", postings)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(postings) != tt.wantCount {
+				t.Fatalf("got %d postings, want %d", len(postings), tt.wantCount)
+			}
+
+			var sum int64
+			for _, p := range postings {
+				sum += int64(math.Round(p.Amount * 100))
+			}
+			if sum != 0 {
+				t.Errorf("postings sum up to %d cents", sum)
+			}
+		})
+	}
+}
+
+func TestBalancePostingsOrder(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	if b.String() < a.String() {
+		a, b = b, a
+	}
+
+	postings, err := balancePostings(ledgerTransfer{Kind: "test", Postings: []ledgerPosting{
+		{UserID: b, Account: AccountWallet, Amount: 1},
+		{UserID: a, Account: AccountWallet, Amount: 1},
+		{UserID: b, Account: AccountEscrow, Amount: -2},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []struct {
+		account string
+		user    uuid.UUID
+	}{{AccountEscrow, b}, {AccountWallet, a}, {AccountWallet, b}}
+	for i, w := range want {
+		if postings[i].Account != w.account || postings[i].UserID != w.user {
+			t.Errorf("posting %d is %s of %s, want %s of %s", i, postings[i].Account, postings[i].UserID, w.account, w.user)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"go-server/internal/config"
+	"go-server/pkg/client/postgresql"
+	"go-server/pkg/logging"
+)
+
+const NotificationWishlistMatch = "wishlist_match"
+
+var (
+	ErrItemNotFound          = errors.New("item not found")
+	ErrWishlistEntryNotFound = errors.New("wishlist entry not found")
+)
+
+type RepositoryWishlist struct {
+	client postgresql.Client
+	logger *logging.Logger
+}
+
+type WishlistData struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ItemID    uuid.UUID `json:"item_id"`
+	MaxRarity *string   `json:"max_rarity,omitempty"`
+	Quality   *string   `json:"quality,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func NewRepositoryWishlist(logger *logging.Logger) *RepositoryWishlist {
+	cfg := config.GetConfig()
+	client, err := postgresql.NewClient(context.TODO(), 3, cfg.Storage)
+	if err != nil {
+		logger.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	logger.Info("connected to PostgreSQL")
+
+	return &RepositoryWishlist{
+		client: client,
+		logger: logger,
+	}
+}
+
+// Upsert adds the item to the user's wishlist or replaces the constraints of
+// the existing entry.
+func (r *RepositoryWishlist) Upsert(ctx context.Context, data WishlistData) (WishlistData, error) {
+	q := `
+		INSERT INTO public.wishlist (
+			id,
+			user_id,
+			item_id,
+			max_rarity,
+			quality,
+			created_at
+		)
+		VALUES (
+			gen_random_uuid(),
+			$1,
+			$2,
+			$3,
+			$4,
+			CURRENT_TIMESTAMP
+		)
+		ON CONFLICT (user_id, item_id) DO UPDATE SET
+			max_rarity = EXCLUDED.max_rarity,
+			quality = EXCLUDED.quality
+		RETURNING created_at
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	if err := r.client.QueryRow(ctx, q, data.UserID, data.ItemID, data.MaxRarity, data.Quality).Scan(&data.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return WishlistData{}, fmt.Errorf("%w: %s", ErrItemNotFound, data.ItemID)
+		}
+		return WishlistData{}, err
+	}
+
+	return data, nil
+}
+
+func (r *RepositoryWishlist) FindByUserID(ctx context.Context, userID string) ([]WishlistData, error) {
+	q := `
+		SELECT
+			user_id,
+			item_id,
+			max_rarity,
+			quality,
+			created_at
+		FROM public.wishlist
+		WHERE
+			user_id = $1
+		ORDER BY created_at, item_id
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	rows, err := r.client.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]WishlistData, 0)
+	for rows.Next() {
+		var w WishlistData
+
+		if err := rows.Scan(&w.UserID, &w.ItemID, &w.MaxRarity, &w.Quality, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (r *RepositoryWishlist) Delete(ctx context.Context, userID, itemID string) error {
+	q := `
+		DELETE FROM public.wishlist
+		WHERE
+			user_id = $1
+		AND
+			item_id = $2
+	`
+	r.logger.Trace(fmt.Sprintf("SQL Query: %s", formatQuery(q)))
+
+	tag, err := r.client.Exec(ctx, q, userID, itemID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrWishlistEntryNotFound
+	}
+
+	return nil
+}
+
+// notifyWishlists creates a notification within tx for every user whose
+// wishlist holds an item offered in the trade and whose rarity and quality
+// constraints the item meets. Private trades only notify their recipient and
+// the owner is never notified of their own trade.
+func notifyWishlists(ctx context.Context, tx pgx.Tx, tradeID uuid.UUID) error {
+	q := `
+		INSERT INTO public.notification (
+			id,
+			user_id,
+			kind,
+			trade_id,
+			item_id,
+			created_at
+		)
+		SELECT
+			gen_random_uuid(),
+			w.user_id,
+			$2,
+			t.id,
+			w.item_id,
+			CURRENT_TIMESTAMP
+		FROM public.trade t
+		JOIN public.trade_item ti ON ti.trade_id = t.id AND ti.item_status = 'offered'
+		JOIN public.wishlist w ON w.item_id = ti.item_id
+		JOIN public.item i ON i.id = ti.item_id
+		WHERE
+			t.id = $1
+		AND
+			w.user_id <> t.user_id
+		AND
+			(t.recipient_id IS NULL OR t.recipient_id = w.user_id)
+		AND
+			(w.max_rarity IS NULL OR array_position($3::text[], lower(i.rarity)) <= array_position($3::text[], lower(w.max_rarity)))
+		AND
+			(w.quality IS NULL OR lower(i.quality) = lower(w.quality))
+	`
+
+	if _, err := tx.Exec(ctx, q, tradeID, NotificationWishlistMatch, ItemRarities); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -14,8 +14,45 @@ import (
 const (
 	tradesURL     = "/api/trades"
 	tradeURL      = "/api/trades/:uuid"
+	acceptURL     = "/api/trades/:uuid/accept"
+	rejectURL     = "/api/trades/:uuid/reject"
+	cancelURL     = "/api/trades/:uuid/cancel"
+	completeURL   = "/api/trades/:uuid/complete"
+	counterURL    = "/api/trades/:uuid/counter"
+	threadURL     = "/api/trades/:uuid/negotiation"
+	matchesURL    = "/api/trades/:uuid/matches"
+	historyURL    = "/api/trades/:uuid/history"
+	messagesURL   = "/api/trades/:uuid/messages"
+	receiptURL    = "/api/trades/:uuid/receipt"
+	ratingURL     = "/api/trades/:uuid/rating"
+	receiptKeyURL = "/api/receipts/public-key"
 	usertradesURL = "/api/users/:uuid/trades"
+	incomingURL   = "/api/users/:uuid/trades/incoming"
+	outgoingURL   = "/api/users/:uuid/trades/outgoing"
 	itemtradesURL = "/api/items/:uuid/trades"
+	orderbookURL  = "/api/items/:uuid/orderbook"
+	itemPricesURL = "/api/items/:uuid/prices"
+	inventoryURL  = "/api/users/:uuid/inventory"
+	wishlistURL   = "/api/users/:uuid/wishlist"
+	wishItemURL   = "/api/users/:uuid/wishlist/:item"
+	notifyURL     = "/api/users/:uuid/notifications"
+	notifyReadURL = "/api/users/:uuid/notifications/:notification/read"
+	walletURL     = "/api/users/:uuid/wallet"
+	ratingsURL    = "/api/users/:uuid/ratings"
+
+	listingsURL        = "/api/listings"
+	listingURL         = "/api/listings/:uuid"
+	listingBuyURL      = "/api/listings/:uuid/buy"
+	listingWithdrawURL = "/api/listings/:uuid/withdraw"
+
+	auctionsURL      = "/api/auctions"
+	auctionURL       = "/api/auctions/:uuid"
+	auctionBidsURL   = "/api/auctions/:uuid/bids"
+	auctionCancelURL = "/api/auctions/:uuid/cancel"
+
+	ordersURL      = "/api/orders"
+	orderURL       = "/api/orders/:uuid"
+	orderCancelURL = "/api/orders/:uuid/cancel"
 
 	itemsURL = "/api/items"
 	itemURL  = "/api/items/:uuid"
@@ -29,12 +66,23 @@ const (
 	loginURL    = "/api/login"
 	logoutURL   = "/api/logout"
 
-	usersURLAdmin  = "/api/admin/users"
-	userURLAdmin   = "/api/admin/users/:uuid"
-	itemsURLAdmin  = "/api/admin/items"
-	itemURLAdmin   = "/api/admin/items/:uuid"
-	tradeURLAdmin  = "/api/admin/trades/:uuid"
-	tradesURLAdmin = "/api/admin/trades"
+	usersURLAdmin     = "/api/admin/users"
+	userURLAdmin      = "/api/admin/users/:uuid"
+	inventoryURLAdmin = "/api/admin/users/:uuid/inventory"
+	walletURLAdmin    = "/api/admin/users/:uuid/wallet"
+	itemsURLAdmin     = "/api/admin/items"
+	itemURLAdmin      = "/api/admin/items/:uuid"
+	tradeURLAdmin     = "/api/admin/trades/:uuid"
+	tradesURLAdmin    = "/api/admin/trades"
+
+	topItemsURLAdmin   = "/api/admin/stats/items"
+	tradeStatsURLAdmin = "/api/admin/stats/trades"
+	acceptanceURLAdmin = "/api/admin/stats/acceptance"
+	tradersURLAdmin    = "/api/admin/stats/traders"
+
+	reviewsURLAdmin       = "/api/admin/reviews"
+	approveReviewURLAdmin = "/api/admin/reviews/:uuid/approve"
+	rejectReviewURLAdmin  = "/api/admin/reviews/:uuid/reject"
 )
 
 func GetRouter(cfg *config.Config) *httprouter.Router {
@@ -42,17 +90,55 @@ func GetRouter(cfg *config.Config) *httprouter.Router {
 
 	tradeHandler := handlerapi.NewTradeHandler()
 	itemHandler := handlerapi.NewItemHandler()
+	listingHandler := handlerapi.NewListingHandler()
+	auctionHandler := handlerapi.NewAuctionHandler()
+	buyOrderHandler := handlerapi.NewBuyOrderHandler()
 	userHandler := handlerapi.NewUserHandler()
 	authHandler := handlerauth.NewAuthHandler()
 	adminHandler := handleradmin.NewAdminHandler()
 
-	router.GET(itemtradesURL, tradeHandler.GetTradesByItemUUID)
-	router.GET(tradesURL, tradeHandler.GetTradeList)
-	router.POST(tradesURL, tradeHandler.CreateTrade)
-	router.DELETE(tradeURL, tradeHandler.DeleteTradeByUUID)
-	router.GET(tradeURL, tradeHandler.GetTradeByTradeUUID)
-	router.PUT(tradeURL, tradeHandler.UpdateTradeByUUID)
-	router.GET(usertradesURL, tradeHandler.GetTradesByUserUUID)
+	router.GET(itemtradesURL, middleware.OptionalAuthMiddleware(tradeHandler.GetTradesByItemUUID, logging.GetLogger()))
+	router.GET(tradesURL, middleware.OptionalAuthMiddleware(tradeHandler.GetTradeList, logging.GetLogger()))
+	router.POST(tradesURL, middleware.AuthMiddleware(tradeHandler.CreateTrade, logging.GetLogger()))
+	router.DELETE(tradeURL, middleware.AuthMiddleware(tradeHandler.DeleteTradeByUUID, logging.GetLogger()))
+	router.GET(tradeURL, middleware.OptionalAuthMiddleware(tradeHandler.GetTradeByTradeUUID, logging.GetLogger()))
+	router.PUT(tradeURL, middleware.AuthMiddleware(tradeHandler.UpdateTradeByUUID, logging.GetLogger()))
+	router.POST(acceptURL, middleware.AuthMiddleware(tradeHandler.AcceptTrade, logging.GetLogger()))
+	router.POST(rejectURL, middleware.AuthMiddleware(tradeHandler.RejectTrade, logging.GetLogger()))
+	router.POST(cancelURL, middleware.AuthMiddleware(tradeHandler.CancelTrade, logging.GetLogger()))
+	router.POST(completeURL, middleware.AuthMiddleware(tradeHandler.CompleteTrade, logging.GetLogger()))
+	router.POST(counterURL, middleware.AuthMiddleware(tradeHandler.CreateCounterOffer, logging.GetLogger()))
+	router.GET(threadURL, middleware.OptionalAuthMiddleware(tradeHandler.GetNegotiation, logging.GetLogger()))
+	router.GET(matchesURL, middleware.OptionalAuthMiddleware(tradeHandler.GetTradeMatches, logging.GetLogger()))
+	router.GET(historyURL, middleware.OptionalAuthMiddleware(tradeHandler.GetTradeHistory, logging.GetLogger()))
+	router.GET(messagesURL, middleware.AuthMiddleware(tradeHandler.GetTradeMessages, logging.GetLogger()))
+	router.GET(receiptURL, middleware.OptionalAuthMiddleware(tradeHandler.GetTradeReceipt, logging.GetLogger()))
+	router.GET(receiptKeyURL, tradeHandler.GetReceiptPublicKey)
+	router.POST(ratingURL, middleware.AuthMiddleware(tradeHandler.RateTrade, logging.GetLogger()))
+	router.POST(messagesURL, middleware.AuthMiddleware(tradeHandler.PostTradeMessage, logging.GetLogger()))
+	router.GET(usertradesURL, middleware.OptionalAuthMiddleware(tradeHandler.GetTradesByUserUUID, logging.GetLogger()))
+	router.GET(incomingURL, middleware.AuthMiddleware(tradeHandler.GetIncomingTrades, logging.GetLogger()))
+	router.GET(outgoingURL, middleware.AuthMiddleware(tradeHandler.GetOutgoingTrades, logging.GetLogger()))
+
+	router.GET(listingsURL, listingHandler.GetListingList)
+	router.GET(listingURL, listingHandler.GetListingByUUID)
+	router.POST(listingsURL, middleware.AuthMiddleware(listingHandler.CreateListing, logging.GetLogger()))
+	router.POST(listingBuyURL, middleware.AuthMiddleware(listingHandler.BuyListing, logging.GetLogger()))
+	router.POST(listingWithdrawURL, middleware.AuthMiddleware(listingHandler.WithdrawListing, logging.GetLogger()))
+
+	router.GET(auctionsURL, auctionHandler.GetAuctionList)
+	router.GET(auctionURL, auctionHandler.GetAuctionByUUID)
+	router.POST(auctionsURL, middleware.AuthMiddleware(auctionHandler.CreateAuction, logging.GetLogger()))
+	router.GET(auctionBidsURL, auctionHandler.GetAuctionBids)
+	router.POST(auctionBidsURL, middleware.AuthMiddleware(auctionHandler.PlaceBid, logging.GetLogger()))
+	router.POST(auctionCancelURL, middleware.AuthMiddleware(auctionHandler.CancelAuction, logging.GetLogger()))
+
+	router.GET(orderbookURL, buyOrderHandler.GetOrderBook)
+	router.GET(itemPricesURL, itemHandler.GetItemPrices)
+	router.GET(ordersURL, buyOrderHandler.GetBuyOrderList)
+	router.GET(orderURL, buyOrderHandler.GetBuyOrderByUUID)
+	router.POST(ordersURL, middleware.AuthMiddleware(buyOrderHandler.CreateBuyOrder, logging.GetLogger()))
+	router.POST(orderCancelURL, middleware.AuthMiddleware(buyOrderHandler.CancelBuyOrder, logging.GetLogger()))
 
 	router.GET(itemsURL, middleware.AuthMiddleware(itemHandler.GetItemList, logging.GetLogger()))
 	router.GET(itemURL, middleware.AuthMiddleware(itemHandler.GetItemByUUID, logging.GetLogger()))
@@ -65,6 +151,14 @@ func GetRouter(cfg *config.Config) *httprouter.Router {
 	router.POST(usersURL, userHandler.CreateUser)
 	router.DELETE(userURL, userHandler.DeleteUserByUUID)
 	router.PUT(userURL, userHandler.UpdateUserByUUID)
+	router.GET(inventoryURL, userHandler.GetUserInventory)
+	router.GET(wishlistURL, middleware.AuthMiddleware(userHandler.GetUserWishlist, logging.GetLogger()))
+	router.POST(wishlistURL, middleware.AuthMiddleware(userHandler.AddWishlistItem, logging.GetLogger()))
+	router.DELETE(wishItemURL, middleware.AuthMiddleware(userHandler.DeleteWishlistItem, logging.GetLogger()))
+	router.GET(notifyURL, middleware.AuthMiddleware(userHandler.GetUserNotifications, logging.GetLogger()))
+	router.POST(notifyReadURL, middleware.AuthMiddleware(userHandler.ReadNotification, logging.GetLogger()))
+	router.GET(walletURL, middleware.AuthMiddleware(userHandler.GetUserWallet, logging.GetLogger()))
+	router.GET(ratingsURL, userHandler.GetUserRatings)
 
 	router.POST(registerURL, authHandler.RegisterUser)
 	router.POST(loginURL, authHandler.LoginUser)
@@ -76,6 +170,8 @@ func GetRouter(cfg *config.Config) *httprouter.Router {
 	router.PUT(userURLAdmin, middleware.AuthMiddleware(adminHandler.UpdateUserByUUID, logging.GetLogger()))
 	router.PATCH(userURLAdmin, middleware.AuthMiddleware(adminHandler.UpdateUserRoleByUUID, logging.GetLogger()))
 	router.DELETE(userURLAdmin, adminHandler.DeleteUserByUUID)
+	router.POST(inventoryURLAdmin, middleware.AuthMiddleware(adminHandler.AddInventoryItem, logging.GetLogger()))
+	router.POST(walletURLAdmin, middleware.AuthMiddleware(adminHandler.AdjustUserWallet, logging.GetLogger()))
 	router.POST(itemURLAdmin, adminHandler.CreateItem)
 	router.GET(itemsURLAdmin, adminHandler.GetItemList)
 	router.GET(itemURLAdmin, adminHandler.GetItemByUUID)
@@ -86,6 +182,13 @@ func GetRouter(cfg *config.Config) *httprouter.Router {
 	router.GET(tradeURLAdmin, adminHandler.GetTradeByTradeUUID)
 	router.PUT(tradeURLAdmin, adminHandler.UpdateTradeByUUID)
 	router.DELETE(tradeURLAdmin, adminHandler.DeleteTradeByUUID)
+	router.GET(topItemsURLAdmin, middleware.AuthMiddleware(adminHandler.GetTopTradedItems, logging.GetLogger()))
+	router.GET(tradeStatsURLAdmin, middleware.AuthMiddleware(adminHandler.GetTradeCounts, logging.GetLogger()))
+	router.GET(acceptanceURLAdmin, middleware.AuthMiddleware(adminHandler.GetAcceptanceStats, logging.GetLogger()))
+	router.GET(tradersURLAdmin, middleware.AuthMiddleware(adminHandler.GetTraderStats, logging.GetLogger()))
+	router.GET(reviewsURLAdmin, middleware.AuthMiddleware(adminHandler.GetTradeReviews, logging.GetLogger()))
+	router.POST(approveReviewURLAdmin, middleware.AuthMiddleware(adminHandler.ApproveTradeReview, logging.GetLogger()))
+	router.POST(rejectReviewURLAdmin, middleware.AuthMiddleware(adminHandler.RejectTradeReview, logging.GetLogger()))
 
 	return router
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/go-co-op/gocron/v2"
 
+	"go-server/internal/config"
 	"go-server/internal/models"
 	"go-server/pkg/logging"
 
@@ -35,6 +36,58 @@ func ScheduleTask() {
 
 	fmt.Println(j.ID())
 
+	if _, err = s.NewJob(
+		gocron.DurationJob(config.GetConfig().Trades.ExpireInterval),
+		gocron.NewTask(
+			func() {
+				model.ExpireStaleTrades()
+			},
+		),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	); err != nil {
+		logger.Errorf("Error creating trade expiry job: %v", err)
+		return
+	}
+
+	if _, err = s.NewJob(
+		gocron.DurationJob(config.GetConfig().Auctions.CloseInterval),
+		gocron.NewTask(
+			func() {
+				model.CloseEndedAuctions()
+			},
+		),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	); err != nil {
+		logger.Errorf("Error creating auction close job: %v", err)
+		return
+	}
+
+	if _, err = s.NewJob(
+		gocron.DurationJob(config.GetConfig().PriceHistory.RollupInterval),
+		gocron.NewTask(
+			func() {
+				model.RollupPriceHistory()
+			},
+		),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	); err != nil {
+		logger.Errorf("Error creating price history job: %v", err)
+		return
+	}
+
+	if _, err = s.NewJob(
+		gocron.DurationJob(config.GetConfig().Stats.RefreshInterval),
+		gocron.NewTask(
+			func() {
+				model.RefreshTradeStats()
+			},
+		),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	); err != nil {
+		logger.Errorf("Error creating stats refresh job: %v", err)
+		return
+	}
+
 	s.Start()
 
 	for {
@@ -0,0 +1,5 @@
+-- migrations/004_add_trade_parent.sql
+ALTER TABLE public.trade
+    ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES public.trade(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS trade_parent_id_idx ON public.trade (parent_id);
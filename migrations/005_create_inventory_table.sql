@@ -0,0 +1,12 @@
+-- migrations/005_create_inventory_table.sql
+CREATE TABLE IF NOT EXISTS public.inventory (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES public.user(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL REFERENCES public.item(id),
+    acquired_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX IF NOT EXISTS inventory_user_item_idx ON public.inventory (user_id, item_id);
+
+ALTER TABLE public.trade
+    ADD COLUMN IF NOT EXISTS accepted_by UUID REFERENCES public.user(id);
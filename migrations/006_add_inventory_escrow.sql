@@ -0,0 +1,5 @@
+-- migrations/006_add_inventory_escrow.sql
+ALTER TABLE public.inventory
+    ADD COLUMN IF NOT EXISTS trade_id UUID REFERENCES public.trade(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS inventory_trade_id_idx ON public.inventory (trade_id);
@@ -0,0 +1,9 @@
+-- migrations/007_add_trade_expires_at.sql
+ALTER TABLE public.trade
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+UPDATE public.trade
+SET expires_at = date + INTERVAL '30 days'
+WHERE expires_at IS NULL AND status = 'pending';
+
+CREATE INDEX IF NOT EXISTS trade_pending_expires_at_idx ON public.trade (expires_at) WHERE status = 'pending';
@@ -0,0 +1,6 @@
+-- migrations/008_add_trade_list_indexes.sql
+CREATE INDEX IF NOT EXISTS trade_date_id_idx ON public.trade (date, id);
+CREATE INDEX IF NOT EXISTS trade_status_date_id_idx ON public.trade (status, date, id);
+CREATE INDEX IF NOT EXISTS trade_user_id_date_idx ON public.trade (user_id, date, id);
+CREATE INDEX IF NOT EXISTS trade_item_trade_id_idx ON public.trade_item (trade_id);
+CREATE INDEX IF NOT EXISTS trade_item_item_status_idx ON public.trade_item (item_id, item_status);
@@ -0,0 +1,24 @@
+-- migrations/009_create_trade_event_table.sql
+-- trade_id deliberately has no foreign key: events must outlive deleted trades.
+CREATE TABLE IF NOT EXISTS public.trade_event (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    seq BIGSERIAL NOT NULL,
+    trade_id UUID NOT NULL,
+    event_type VARCHAR(32) NOT NULL,
+    actor_id UUID,
+    payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX IF NOT EXISTS trade_event_trade_id_idx ON public.trade_event (trade_id, created_at, seq);
+
+CREATE OR REPLACE FUNCTION public.trade_event_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'trade_event is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trade_event_append_only ON public.trade_event;
+CREATE TRIGGER trade_event_append_only
+    BEFORE UPDATE OR DELETE ON public.trade_event
+    FOR EACH ROW EXECUTE FUNCTION public.trade_event_append_only();
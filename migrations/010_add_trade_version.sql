@@ -0,0 +1,3 @@
+-- migrations/010_add_trade_version.sql
+ALTER TABLE public.trade
+    ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,24 @@
+-- migrations/011_add_trade_item_quantity.sql
+ALTER TABLE public.trade_item
+    ADD COLUMN IF NOT EXISTS quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0);
+
+-- Lines that repeated the same item on one side become a single line with a quantity.
+UPDATE public.trade_item ti
+SET quantity = d.n
+FROM (
+    SELECT trade_id, item_id, item_status, count(*) AS n, min(id::text) AS keep_id
+    FROM public.trade_item
+    GROUP BY trade_id, item_id, item_status
+    HAVING count(*) > 1
+) d
+WHERE ti.id::text = d.keep_id;
+
+DELETE FROM public.trade_item ti
+USING public.trade_item other
+WHERE ti.trade_id = other.trade_id
+  AND ti.item_id = other.item_id
+  AND ti.item_status = other.item_status
+  AND ti.id::text > other.id::text;
+
+CREATE UNIQUE INDEX IF NOT EXISTS trade_item_trade_item_status_key
+    ON public.trade_item (trade_id, item_id, item_status);
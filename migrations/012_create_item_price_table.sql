@@ -0,0 +1,27 @@
+-- migrations/012_create_item_price_table.sql
+CREATE TABLE IF NOT EXISTS public.item_price (
+    name VARCHAR(100) PRIMARY KEY,
+    currency VARCHAR(10) NOT NULL,
+    average_24h DOUBLE PRECISION NOT NULL DEFAULT 0,
+    median_24h DOUBLE PRECISION NOT NULL DEFAULT 0,
+    lowest_24h DOUBLE PRECISION NOT NULL DEFAULT 0,
+    highest_24h DOUBLE PRECISION NOT NULL DEFAULT 0,
+    average_7d DOUBLE PRECISION NOT NULL DEFAULT 0,
+    median_7d DOUBLE PRECISION NOT NULL DEFAULT 0,
+    lowest_7d DOUBLE PRECISION NOT NULL DEFAULT 0,
+    highest_7d DOUBLE PRECISION NOT NULL DEFAULT 0,
+    average_30d DOUBLE PRECISION NOT NULL DEFAULT 0,
+    median_30d DOUBLE PRECISION NOT NULL DEFAULT 0,
+    lowest_30d DOUBLE PRECISION NOT NULL DEFAULT 0,
+    highest_30d DOUBLE PRECISION NOT NULL DEFAULT 0,
+    average_all_time DOUBLE PRECISION NOT NULL DEFAULT 0,
+    median_all_time DOUBLE PRECISION NOT NULL DEFAULT 0,
+    lowest_all_time DOUBLE PRECISION NOT NULL DEFAULT 0,
+    highest_all_time DOUBLE PRECISION NOT NULL DEFAULT 0,
+    opskins_average DOUBLE PRECISION NOT NULL DEFAULT 0,
+    -- price used to estimate the value of trades, NULL if the item has no sales
+    reference_price DOUBLE PRECISION,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS item_name_idx ON public.item (name);
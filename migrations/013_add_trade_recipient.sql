@@ -0,0 +1,6 @@
+-- migrations/013_add_trade_recipient.sql
+ALTER TABLE public.trade
+    ADD COLUMN IF NOT EXISTS recipient_id UUID REFERENCES public.user(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS trade_recipient_id_idx ON public.trade (recipient_id, date DESC, id DESC)
+    WHERE recipient_id IS NOT NULL;
@@ -0,0 +1,11 @@
+-- migrations/014_create_trade_message_table.sql
+-- trade_id has no foreign key, see 009_create_trade_event_table.sql.
+CREATE TABLE IF NOT EXISTS public.trade_message (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    trade_id UUID NOT NULL,
+    sender_id UUID NOT NULL REFERENCES public.user(id),
+    body TEXT NOT NULL CHECK (char_length(body) BETWEEN 1 AND 2000),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS trade_message_trade_id_idx ON public.trade_message (trade_id, created_at, id);
@@ -0,0 +1,24 @@
+-- migrations/015_create_wishlist_table.sql
+CREATE TABLE IF NOT EXISTS public.wishlist (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES public.user(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL REFERENCES public.item(id) ON DELETE CASCADE,
+    max_rarity VARCHAR(20),
+    quality VARCHAR(100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, item_id)
+);
+
+CREATE INDEX IF NOT EXISTS wishlist_item_id_idx ON public.wishlist (item_id);
+
+CREATE TABLE IF NOT EXISTS public.notification (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES public.user(id) ON DELETE CASCADE,
+    kind VARCHAR(30) NOT NULL,
+    trade_id UUID REFERENCES public.trade(id) ON DELETE CASCADE,
+    item_id UUID REFERENCES public.item(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    read_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS notification_user_id_idx ON public.notification (user_id, created_at DESC, id DESC);
@@ -0,0 +1,22 @@
+-- migrations/016_create_listing_table.sql
+CREATE TABLE IF NOT EXISTS public.listing (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    seller_id UUID NOT NULL REFERENCES public.user(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL REFERENCES public.item(id),
+    price NUMERIC(14, 2) NOT NULL CHECK (price > 0),
+    currency VARCHAR(10) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'sold', 'withdrawn')),
+    buyer_id UUID REFERENCES public.user(id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    closed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS listing_status_created_at_idx ON public.listing (status, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS listing_item_id_idx ON public.listing (item_id);
+CREATE INDEX IF NOT EXISTS listing_seller_id_idx ON public.listing (seller_id);
+
+-- An inventory instance put up for sale is held in escrow by its listing.
+ALTER TABLE public.inventory
+    ADD COLUMN IF NOT EXISTS listing_id UUID REFERENCES public.listing(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS inventory_listing_id_idx ON public.inventory (listing_id);
@@ -0,0 +1,37 @@
+-- migrations/017_create_auction_table.sql
+CREATE TABLE IF NOT EXISTS public.auction (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    seller_id UUID NOT NULL REFERENCES public.user(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL REFERENCES public.item(id),
+    start_price NUMERIC(14, 2) NOT NULL CHECK (start_price > 0),
+    min_increment NUMERIC(14, 2) NOT NULL CHECK (min_increment > 0),
+    currency VARCHAR(10) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'ended', 'cancelled')),
+    current_bid NUMERIC(14, 2),
+    high_bidder_id UUID REFERENCES public.user(id),
+    bid_count INTEGER NOT NULL DEFAULT 0,
+    ends_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    closed_at TIMESTAMPTZ,
+    -- trade created for the winner when the auction ended
+    trade_id UUID REFERENCES public.trade(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS auction_active_ends_at_idx ON public.auction (ends_at) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS auction_status_created_at_idx ON public.auction (status, created_at DESC, id DESC);
+
+CREATE TABLE IF NOT EXISTS public.auction_bid (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    auction_id UUID NOT NULL REFERENCES public.auction(id) ON DELETE CASCADE,
+    bidder_id UUID NOT NULL REFERENCES public.user(id) ON DELETE CASCADE,
+    amount NUMERIC(14, 2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS auction_bid_auction_id_idx ON public.auction_bid (auction_id, created_at DESC);
+
+-- An inventory instance put up for auction is held in escrow until it ends.
+ALTER TABLE public.inventory
+    ADD COLUMN IF NOT EXISTS auction_id UUID REFERENCES public.auction(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS inventory_auction_id_idx ON public.inventory (auction_id);
@@ -0,0 +1,20 @@
+-- migrations/018_create_buy_order_table.sql
+CREATE TABLE IF NOT EXISTS public.buy_order (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    buyer_id UUID NOT NULL REFERENCES public.user(id) ON DELETE CASCADE,
+    item_id UUID NOT NULL REFERENCES public.item(id),
+    price NUMERIC(14, 2) NOT NULL CHECK (price > 0),
+    currency VARCHAR(10) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'filled', 'cancelled')),
+    -- listing bought when the order was filled
+    listing_id UUID REFERENCES public.listing(id) ON DELETE SET NULL,
+    fill_price NUMERIC(14, 2),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    closed_at TIMESTAMPTZ
+);
+
+-- Matching walks the active orders of an item from the best price down.
+CREATE INDEX IF NOT EXISTS buy_order_book_idx ON public.buy_order (item_id, currency, price DESC, created_at, id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS buy_order_buyer_id_idx ON public.buy_order (buyer_id);
+
+CREATE INDEX IF NOT EXISTS listing_book_idx ON public.listing (item_id, currency, price, created_at, id) WHERE status = 'active';
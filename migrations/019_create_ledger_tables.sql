@@ -0,0 +1,63 @@
+-- migrations/019_create_ledger_tables.sql
+
+-- Balances live in ledger accounts. Every user has a "wallet" account with
+-- the available funds and an "escrow" account with funds held by open buy
+-- orders and auction bids, one pair per currency. Money entering or leaving
+-- the platform goes through the "external" account of the nil user.
+-- user_id is not a foreign key: the ledger outlives deleted users.
+CREATE TABLE IF NOT EXISTS public.ledger_account (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('wallet', 'escrow', 'external')),
+    currency VARCHAR(10) NOT NULL,
+    balance NUMERIC(14, 2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT ledger_account_funds_check CHECK (kind = 'external' OR balance >= 0),
+    UNIQUE (kind, user_id, currency)
+);
+
+CREATE TABLE IF NOT EXISTS public.ledger_transaction (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    kind VARCHAR(30) NOT NULL,
+    -- listing, buy order or auction that caused the transaction
+    reference_id UUID,
+    currency VARCHAR(10) NOT NULL,
+    memo TEXT,
+    created_by UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS ledger_transaction_reference_id_idx ON public.ledger_transaction (reference_id);
+
+-- The entries of a transaction always sum up to zero.
+CREATE TABLE IF NOT EXISTS public.ledger_entry (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    transaction_id UUID NOT NULL REFERENCES public.ledger_transaction(id),
+    account_id UUID NOT NULL REFERENCES public.ledger_account(id),
+    amount NUMERIC(14, 2) NOT NULL CHECK (amount <> 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS ledger_entry_account_id_idx ON public.ledger_entry (account_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS ledger_entry_transaction_id_idx ON public.ledger_entry (transaction_id);
+
+CREATE OR REPLACE FUNCTION public.ledger_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger rows cannot be changed';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_transaction_immutable ON public.ledger_transaction;
+CREATE TRIGGER ledger_transaction_immutable
+    BEFORE UPDATE OR DELETE ON public.ledger_transaction
+    FOR EACH ROW EXECUTE FUNCTION public.ledger_immutable();
+
+DROP TRIGGER IF EXISTS ledger_entry_immutable ON public.ledger_entry;
+CREATE TRIGGER ledger_entry_immutable
+    BEFORE UPDATE OR DELETE ON public.ledger_entry
+    FOR EACH ROW EXECUTE FUNCTION public.ledger_immutable();
+
+-- Winning bids stay in escrow until the auction trade is completed
+-- ('paid') or falls through ('refunded').
+ALTER TABLE public.auction
+    ADD COLUMN IF NOT EXISTS settlement VARCHAR(20) CHECK (settlement IN ('paid', 'refunded'));
@@ -0,0 +1,37 @@
+-- migrations/020_create_item_price_history_table.sql
+
+-- Every catalog import keeps a copy of the prices it brought in, so the
+-- history can be rebuilt and trades can be valued at the price of their day.
+CREATE TABLE IF NOT EXISTS public.item_price_snapshot (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    average_24h DOUBLE PRECISION NOT NULL DEFAULT 0,
+    lowest_24h DOUBLE PRECISION NOT NULL DEFAULT 0,
+    highest_24h DOUBLE PRECISION NOT NULL DEFAULT 0,
+    reference_price DOUBLE PRECISION,
+    captured_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS item_price_snapshot_name_idx ON public.item_price_snapshot (name, captured_at DESC);
+CREATE INDEX IF NOT EXISTS item_price_snapshot_captured_at_idx ON public.item_price_snapshot (captured_at);
+
+-- Price history per item in hourly, daily and weekly buckets, rolled up from
+-- the import snapshots and the implied prices of completed trades.
+CREATE TABLE IF NOT EXISTS public.item_price_history (
+    item_id UUID NOT NULL REFERENCES public.item(id) ON DELETE CASCADE,
+    bucket VARCHAR(10) NOT NULL CHECK (bucket IN ('hour', 'day', 'week')),
+    bucket_start TIMESTAMPTZ NOT NULL,
+    currency VARCHAR(10) NOT NULL,
+    average DOUBLE PRECISION NOT NULL,
+    lowest DOUBLE PRECISION NOT NULL,
+    highest DOUBLE PRECISION NOT NULL,
+    samples INTEGER NOT NULL,
+    import_samples INTEGER NOT NULL,
+    trade_samples INTEGER NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (item_id, bucket, bucket_start, currency)
+);
+
+CREATE INDEX IF NOT EXISTS trade_event_completed_idx ON public.trade_event (created_at)
+    WHERE event_type = 'status_changed' AND payload->>'to' = 'completed';
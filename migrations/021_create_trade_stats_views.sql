@@ -0,0 +1,33 @@
+-- migrations/021_create_trade_stats_views.sql
+
+-- Trades per creation day and current status. Refreshed by the scheduler,
+-- so the counts lag behind by up to stats.refresh_interval.
+CREATE MATERIALIZED VIEW IF NOT EXISTS public.trade_status_daily AS
+    SELECT
+        date::date AS day,
+        status,
+        count(*) AS trades
+    FROM public.trade
+    GROUP BY date::date, status;
+
+CREATE UNIQUE INDEX IF NOT EXISTS trade_status_daily_key ON public.trade_status_daily (day, status);
+
+-- Completed trades and traded quantity per item and completion day.
+CREATE MATERIALIZED VIEW IF NOT EXISTS public.item_trade_volume_daily AS
+    SELECT
+        c.completed_at::date AS day,
+        ti.item_id,
+        count(DISTINCT ti.trade_id) AS trades,
+        sum(ti.quantity) AS quantity
+    FROM (
+        SELECT trade_id, max(created_at) AS completed_at
+        FROM public.trade_event
+        WHERE event_type = 'status_changed' AND payload->>'to' = 'completed'
+        GROUP BY trade_id
+    ) c
+    JOIN public.trade_item ti ON ti.trade_id = c.trade_id
+    GROUP BY c.completed_at::date, ti.item_id;
+
+CREATE UNIQUE INDEX IF NOT EXISTS item_trade_volume_daily_key ON public.item_trade_volume_daily (day, item_id);
+
+CREATE INDEX IF NOT EXISTS trade_event_created_at_idx ON public.trade_event (created_at);
@@ -0,0 +1,25 @@
+-- migrations/022_create_trade_receipt_table.sql
+-- A signed receipt is issued when a trade completes. trade_id has no foreign
+-- key, see 009_create_trade_event_table.sql.
+-- payload holds the exact signed bytes, so it is stored as text, not JSONB.
+CREATE TABLE IF NOT EXISTS public.trade_receipt (
+    trade_id UUID PRIMARY KEY,
+    owner_id UUID NOT NULL,
+    counterparty_id UUID,
+    recipient_id UUID,
+    payload TEXT NOT NULL,
+    signature TEXT NOT NULL,
+    key_id VARCHAR(32) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp
+);
+
+CREATE OR REPLACE FUNCTION public.trade_receipt_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'trade receipts cannot be changed';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trade_receipt_immutable ON public.trade_receipt;
+CREATE TRIGGER trade_receipt_immutable
+    BEFORE UPDATE OR DELETE ON public.trade_receipt
+    FOR EACH ROW EXECUTE FUNCTION public.trade_receipt_immutable();
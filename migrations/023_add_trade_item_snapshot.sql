@@ -0,0 +1,18 @@
+-- migrations/023_add_trade_item_snapshot.sql
+-- Attributes of the item as they were when the trade line was written. Closed
+-- trades are shown with them, so renaming or deleting an item later does not
+-- change what was traded.
+ALTER TABLE public.trade_item
+    ADD COLUMN IF NOT EXISTS item_name VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS item_rarity VARCHAR(20),
+    ADD COLUMN IF NOT EXISTS item_quality VARCHAR(1000);
+
+-- Best effort for existing lines: the current attributes are all we have.
+UPDATE public.trade_item ti
+SET
+    item_name = i.name,
+    item_rarity = i.rarity,
+    item_quality = i.quality
+FROM public.item i
+WHERE i.id = ti.item_id
+  AND ti.item_name IS NULL;
@@ -0,0 +1,14 @@
+-- migrations/024_add_user_created_at.sql
+ALTER TABLE public.user
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+
+-- The registration date of existing accounts is unknown: date them back to
+-- their first trade, or to the epoch, so account age rules do not treat them
+-- as new.
+UPDATE public.user u
+SET created_at = COALESCE((SELECT min(t.date) FROM public.trade t WHERE t.user_id = u.id), 'epoch')
+WHERE u.created_at IS NULL;
+
+ALTER TABLE public.user
+    ALTER COLUMN created_at SET DEFAULT current_timestamp,
+    ALTER COLUMN created_at SET NOT NULL;
@@ -0,0 +1,24 @@
+-- migrations/025_create_trade_review_table.sql
+-- A trade flagged by the fraud checks is held for review: while a review of
+-- it is open the trade can be neither accepted nor completed. stage is the
+-- moment the trade was flagged ("created" or "accepted"), signals the checks
+-- that fired. trade_id has no foreign key, see 009_create_trade_event_table.sql.
+CREATE TABLE IF NOT EXISTS public.trade_review (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    trade_id UUID NOT NULL,
+    stage VARCHAR(16) NOT NULL CHECK (stage IN ('created', 'accepted')),
+    score INTEGER NOT NULL,
+    signals JSONB NOT NULL DEFAULT '[]'::jsonb,
+    status VARCHAR(16) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'approved', 'rejected')),
+    reviewer_id UUID REFERENCES public.user(id),
+    note TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS trade_review_open_idx ON public.trade_review (trade_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS trade_review_status_idx ON public.trade_review (status, created_at, id);
+
+-- Lookups of the fraud checks.
+CREATE INDEX IF NOT EXISTS trade_event_actor_id_idx ON public.trade_event (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS trade_accepted_by_idx ON public.trade (accepted_by, date);
@@ -0,0 +1,16 @@
+-- migrations/026_create_trade_rating_table.sql
+-- Each party of a completed trade may rate the other one once. trade_id has
+-- no foreign key, see 009_create_trade_event_table.sql.
+CREATE TABLE IF NOT EXISTS public.trade_rating (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    trade_id UUID NOT NULL,
+    rater_id UUID NOT NULL REFERENCES public.user(id) ON DELETE CASCADE,
+    ratee_id UUID NOT NULL REFERENCES public.user(id) ON DELETE CASCADE,
+    score SMALLINT NOT NULL CHECK (score BETWEEN 1 AND 5),
+    comment TEXT CHECK (char_length(comment) <= 1000),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT current_timestamp,
+    UNIQUE (trade_id, rater_id),
+    CHECK (rater_id <> ratee_id)
+);
+
+CREATE INDEX IF NOT EXISTS trade_rating_ratee_id_idx ON public.trade_rating (ratee_id, created_at, id);